@@ -0,0 +1,463 @@
+// Package backtest replays recorded market data through a strats.Strategy,
+// simulating executions against the recorded top of book and trades.
+package backtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/strats"
+)
+
+// Config controls how orders are simulated.
+type Config struct {
+	Account string
+	Venue   sfclient.Venue
+	Stock   sfclient.Symbol
+
+	// Latency is the delay between the strategy sending an order or cancel
+	// and it taking effect on the book. With a non-zero latency the response
+	// to an order only acknowledges it; executions are reported through
+	// OnFill once the order arrives.
+	Latency time.Duration
+
+	// QueueAhead is the fraction of the displayed size at our price assumed
+	// to be in front of a newly resting order: 0 puts us at the front of the
+	// queue and 1 at the back.
+	QueueAhead float64
+
+	// TimerInterval is how often OnTimer is called, in recorded time. 0
+	// disables the timer.
+	TimerInterval time.Duration
+}
+
+type simOrder struct {
+	sfclient.OrderResponse
+	typ      sfclient.OrderType
+	isBuy    bool
+	activeAt time.Time
+	active   bool
+
+	// arrivedAt is when the order reached the book. Only executions printed
+	// after it can fill it.
+	arrivedAt time.Time
+
+	// cancelAt is the time a pending cancel takes effect, zero if none
+	cancelAt time.Time
+
+	// queue is the number of shares assumed ahead of us at our price, -1
+	// while unknown because our price has not been at the top of the book
+	queue int
+}
+
+// Engine is a simulated exchange for a single stock. It implements
+// strats.Trader so a strategy can trade against it exactly as it would
+// against a StockHub.
+type Engine struct {
+	cfg      Config
+	strategy strats.Strategy
+
+	now    time.Time
+	touch  sfclient.Touch
+	nextID int
+	byID   map[int]*simOrder
+
+	// orders that are open or still in flight to the book
+	orders []*simOrder
+
+	// fills generated while the strategy was being called back, delivered
+	// once the callback returns so callbacks are never re-entered
+	pending []*sfclient.FillMessage
+
+	report *Report
+}
+
+// New creates an engine for cfg.
+func New(cfg Config) *Engine {
+	return &Engine{cfg: cfg}
+}
+
+// ReadTicks decodes newline delimited JSON tick messages from r.
+func ReadTicks(r io.Reader) ([]*sfclient.TickMessage, error) {
+	var ticks []*sfclient.TickMessage
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		msg := &sfclient.TickMessage{}
+		err := dec.Decode(msg)
+		if err == io.EOF {
+			return ticks, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ticks = append(ticks, msg)
+	}
+}
+
+// Run feeds ticks through s in QuoteTime order and reports how it
+// performed. Recordings are written in the order ticks arrived, which need
+// not be the order they were quoted in, so ticks are sorted first; those
+// quoted at the same time keep their order.
+func (e *Engine) Run(s strats.Strategy, ticks []*sfclient.TickMessage) (*Report, error) {
+	return e.RunWithFills(s, ticks, nil)
+}
+
+// RunWithFills is Run with the executions recorded alongside the ticks,
+// which are likewise sorted into FilledAt order. A tick only shows the last
+// trade before it, so these are the trades between ticks that resting
+// orders could also have filled against. Each is printed at the time it
+// happened; the same execution reported for both of its orders is printed
+// once.
+func (e *Engine) RunWithFills(s strats.Strategy, ticks []*sfclient.TickMessage, fills []*sfclient.FillMessage) (*Report, error) {
+	ticks = sortedTicks(ticks)
+	if len(ticks) == 0 {
+		return nil, errors.New("no ticks to replay")
+	}
+
+	e.strategy = s
+	e.touch = sfclient.Touch{}
+	e.orders = nil
+	e.byID = make(map[int]*simOrder)
+	e.pending = nil
+	e.report = &Report{}
+	e.now = ticks[0].Quote.QuoteTime
+
+	if err := s.Start(e); err != nil {
+		return nil, err
+	}
+
+	fills = uniqueExecutions(fills)
+	nextTimer := e.now.Add(e.cfg.TimerInterval)
+	for _, tick := range ticks {
+		// Timers and executions due before this tick happen against the
+		// previous quote, in the order they were due
+		for {
+			timerDue := e.cfg.TimerInterval > 0 && !nextTimer.After(tick.Quote.QuoteTime)
+			fillDue := len(fills) > 0 && !fills[0].FilledAt.After(tick.Quote.QuoteTime)
+			if fillDue && (!timerDue || fills[0].FilledAt.Before(nextTimer)) {
+				e.print(fills[0])
+				fills = fills[1:]
+			} else if timerDue {
+				e.now = nextTimer
+				e.expireCancels()
+				s.OnTimer(nextTimer)
+				e.deliver()
+				nextTimer = nextTimer.Add(e.cfg.TimerInterval)
+			} else {
+				break
+			}
+		}
+
+		e.now = tick.Quote.QuoteTime
+		e.touch.Update(tick.Quote)
+		e.report.Ticks++
+
+		e.expireCancels()
+		e.arrive()
+		e.matchResting()
+		e.deliver()
+
+		s.OnTick(tick)
+		e.deliver()
+
+		e.report.mark(e.touch.Quote())
+	}
+
+	s.Stop()
+	e.deliver()
+	e.report.finish(e.touch.Quote())
+	return e.report, nil
+}
+
+// print executes resting orders against a recorded execution.
+func (e *Engine) print(fill *sfclient.FillMessage) {
+	e.now = fill.FilledAt
+	e.expireCancels()
+	e.arrive()
+	e.touch.Print(fill.FilledAt, fill.Price, fill.Filled)
+	e.matchResting()
+	e.deliver()
+}
+
+// uniqueExecutions drops the second report of an execution between two of
+// the account's own orders.
+// sortedTicks returns the valid ticks in QuoteTime order, leaving ticks as
+// it was.
+func sortedTicks(ticks []*sfclient.TickMessage) []*sfclient.TickMessage {
+	var sorted []*sfclient.TickMessage
+	for _, t := range ticks {
+		if t.OK {
+			sorted = append(sorted, t)
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b *sfclient.TickMessage) int {
+		return a.Quote.QuoteTime.Compare(b.Quote.QuoteTime)
+	})
+	return sorted
+}
+
+// uniqueExecutions returns the valid fills in FilledAt order, keeping one
+// of each execution reported for both of its orders.
+func uniqueExecutions(fills []*sfclient.FillMessage) []*sfclient.FillMessage {
+	type execution struct {
+		at                 time.Time
+		standing, incoming int
+		price, qty         int
+	}
+	seen := make(map[execution]bool)
+
+	var unique []*sfclient.FillMessage
+	for _, f := range fills {
+		if !f.OK {
+			continue
+		}
+		ex := execution{f.FilledAt, f.StandingID, f.IncomingID, f.Price, f.Filled}
+		if seen[ex] {
+			continue
+		}
+		seen[ex] = true
+		unique = append(unique, f)
+	}
+	slices.SortStableFunc(unique, func(a, b *sfclient.FillMessage) int {
+		return a.FilledAt.Compare(b.FilledAt)
+	})
+	return unique
+}
+
+func (e *Engine) deliver() {
+	for len(e.pending) > 0 {
+		fill := e.pending[0]
+		e.pending = e.pending[1:]
+		e.strategy.OnFill(fill)
+		e.strategy.OnOrderUpdate(&fill.Order)
+	}
+}
+
+func (e *Engine) place(direction string, price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	if qty <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	e.nextID++
+	o := &simOrder{
+		OrderResponse: sfclient.OrderResponse{
+			APIResponse:      sfclient.APIResponse{OK: true},
+			Symbol:           e.cfg.Stock,
+			Venue:            e.cfg.Venue,
+			Direction:        direction,
+			OriginalQuantity: qty,
+			Quantity:         qty,
+			Price:            price,
+			OrderType:        string(typ),
+			ID:               e.nextID,
+			Account:          e.cfg.Account,
+			Timestamp:        e.now,
+			Open:             true,
+		},
+		typ:      typ,
+		isBuy:    direction == "buy",
+		activeAt: e.now.Add(e.cfg.Latency),
+		queue:    -1,
+	}
+	e.orders = append(e.orders, o)
+	e.byID[o.ID] = o
+	e.report.Orders++
+	e.report.OrderedQty += qty
+
+	if e.cfg.Latency == 0 {
+		e.arrive()
+	}
+
+	resp := o.OrderResponse
+	resp.Fills = append([]sfclient.AskBid(nil), o.Fills...)
+	return &resp, nil
+}
+
+func (e *Engine) Buy(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return e.place("buy", price, qty, typ)
+}
+
+func (e *Engine) Sell(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return e.place("sell", price, qty, typ)
+}
+
+func (e *Engine) Cancel(id int) (*sfclient.CancelOrderResponse, error) {
+	o, ok := e.byID[id]
+	if !ok {
+		return nil, errors.New("no such order")
+	}
+
+	if o.Open && o.cancelAt.IsZero() {
+		o.cancelAt = e.now.Add(e.cfg.Latency)
+		if e.cfg.Latency == 0 {
+			e.expireCancels()
+		}
+	}
+
+	resp := &sfclient.CancelOrderResponse{APIResponse: sfclient.APIResponse{OK: true}}
+	resp.Symbol, resp.Venue, resp.Direction = o.Symbol, o.Venue, o.Direction
+	resp.OriginalQuantity, resp.Price, resp.ID = o.OriginalQuantity, o.Price, o.ID
+	resp.OrderType, resp.Account, resp.Timestamp = sfclient.OrderType(o.OrderType), o.Account, o.Timestamp
+	resp.Fills = append([]sfclient.AskBid(nil), o.Fills...)
+	resp.TotalFilled = o.TotalFilled
+	return resp, nil
+}
+
+// expireCancels removes orders whose cancel has reached the book.
+func (e *Engine) expireCancels() {
+	kept := e.orders[:0]
+	for _, o := range e.orders {
+		if !o.cancelAt.IsZero() && !o.cancelAt.After(e.now) {
+			o.Open = false
+			o.Quantity = 0
+			continue
+		}
+		if !o.Open {
+			continue
+		}
+		kept = append(kept, o)
+	}
+	e.orders = kept
+}
+
+// crosses reports whether o would trade at price.
+func (o *simOrder) crosses(price int) bool {
+	if o.isBuy {
+		return price <= o.Price
+	}
+	return price >= o.Price
+}
+
+// arrive puts orders whose latency has elapsed onto the book, executing
+// any marketable quantity against the current quote.
+func (e *Engine) arrive() {
+	for _, o := range e.orders {
+		if o.active || o.activeAt.After(e.now) {
+			continue
+		}
+		o.active = true
+		o.arrivedAt = e.now
+
+		// Only the displayed size at the top of the book is known, so what
+		// is left of it is all the liquidity a marketable order can take.
+		price, avail := e.touch.Offered(o.isBuy)
+		if price == 0 || (o.typ != sfclient.TypeMarket && !o.crosses(price)) {
+			avail = 0
+		}
+
+		if o.typ == sfclient.TypeFillOrKill && avail < o.Quantity {
+			avail = 0
+		}
+		if qty := min(avail, o.Quantity); qty > 0 {
+			e.touch.Take(o.isBuy, qty)
+			e.fill(o, price, qty, false)
+		}
+
+		if o.typ != sfclient.TypeLimit {
+			// Anything not executed immediately is cancelled
+			o.Open, o.Quantity = false, 0
+			continue
+		}
+
+		e.joinQueue(o)
+	}
+	e.expireCancels()
+}
+
+func (e *Engine) joinQueue(o *simOrder) {
+	q := e.touch.Quote()
+	best, size := q.Bid, q.BidSize
+	better := o.Price > best
+	if !o.isBuy {
+		best, size = q.Ask, q.AskSize
+		better = best == 0 || o.Price < best
+	}
+
+	switch {
+	case better:
+		o.queue = 0
+	case o.Price == best:
+		o.queue = int(e.cfg.QueueAhead * float64(size))
+	}
+}
+
+// matchResting executes resting orders against the size quoted at or
+// through their price and not yet taken, then against what is left of the
+// latest execution. Orders placed first are matched first.
+func (e *Engine) matchResting() {
+	q := e.touch.Quote()
+	for _, o := range e.orders {
+		if !o.active || !o.Open {
+			continue
+		}
+
+		// We only learn our queue position once our price is the best
+		if o.queue < 0 {
+			if (o.isBuy && o.Price == q.Bid) || (!o.isBuy && o.Price == q.Ask) {
+				e.joinQueue(o)
+			}
+		}
+
+		// Someone is quoting at or through our price
+		if price, avail := e.touch.Offered(o.isBuy); price > 0 && o.crosses(price) && avail > 0 {
+			qty := min(avail, o.Quantity)
+			e.touch.Take(o.isBuy, qty)
+			e.fill(o, o.Price, qty, true)
+		}
+
+		at, price, left := e.touch.Traded()
+		if !o.Open || left == 0 || !at.After(o.arrivedAt) {
+			continue
+		}
+
+		var qty int
+		switch {
+		case price != o.Price && o.crosses(price):
+			// Traded through our price, so we must have been hit first
+			qty = left
+		case price == o.Price && o.queue >= 0:
+			qty = left - o.queue
+			o.queue = max(0, o.queue-left)
+		}
+
+		if qty = min(qty, o.Quantity); qty > 0 {
+			e.touch.Allot(qty)
+			e.fill(o, o.Price, qty, true)
+		}
+	}
+	e.expireCancels()
+}
+
+// fill executes qty of o at price, queueing the fill for the strategy.
+func (e *Engine) fill(o *simOrder, price, qty int, standing bool) {
+	o.Quantity -= qty
+	o.TotalFilled += qty
+	o.Fills = append(o.Fills, sfclient.AskBid{Price: price, Quantity: qty, IsBuy: o.isBuy})
+	if o.Quantity == 0 {
+		o.Open = false
+	}
+
+	e.report.fill(o.isBuy, price, qty)
+
+	msg := &sfclient.FillMessage{
+		APIResponse: sfclient.APIResponse{OK: true},
+		Account:     e.cfg.Account,
+		Venue:       e.cfg.Venue,
+		Symbol:      e.cfg.Stock,
+		Order:       o.OrderResponse,
+		Price:       price,
+		Filled:      qty,
+		FilledAt:    e.now,
+	}
+	msg.Order.Fills = append([]sfclient.AskBid(nil), o.Fills...)
+	if standing {
+		msg.StandingID, msg.StandingComplete = o.ID, !o.Open
+	} else {
+		msg.IncomingID, msg.IncomingComplete = o.ID, !o.Open
+	}
+	e.pending = append(e.pending, msg)
+}
@@ -0,0 +1,305 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/strats"
+)
+
+var epoch = time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+func tick(ms, bid, bidSize, ask, askSize, last, lastSize int) *sfclient.TickMessage {
+	msg := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	msg.Quote = sfclient.StockState{
+		Bid: bid, BidSize: bidSize, Ask: ask, AskSize: askSize,
+		Last: last, LastSize: lastSize,
+		LastTrade: epoch.Add(time.Duration(ms) * time.Millisecond),
+		QuoteTime: epoch.Add(time.Duration(ms) * time.Millisecond),
+	}
+	return msg
+}
+
+// scripted calls act on the n-th tick and records everything it is told.
+type scripted struct {
+	strats.BaseStrategy
+	trader  strats.Trader
+	n       int
+	act     map[int]func(t strats.Trader)
+	fills   []*sfclient.FillMessage
+	updates int
+	timers  int
+}
+
+func (s *scripted) Start(t strats.Trader) error {
+	s.trader = t
+	return nil
+}
+
+func (s *scripted) OnTick(msg *sfclient.TickMessage) {
+	if f, ok := s.act[s.n]; ok {
+		f(s.trader)
+	}
+	s.n++
+}
+
+func (s *scripted) OnFill(msg *sfclient.FillMessage)            { s.fills = append(s.fills, msg) }
+func (s *scripted) OnOrderUpdate(order *sfclient.OrderResponse) { s.updates++ }
+func (s *scripted) OnTimer(now time.Time)                       { s.timers++ }
+
+func TestMarketableOrder(t *testing.T) {
+	var resp *sfclient.OrderResponse
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) { resp, _ = tr.Buy(101, 50, sfclient.TypeLimit) },
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 101, 20, 100, 5),
+		// The same offer again, which we have already taken
+		tick(10, 99, 10, 101, 20, 100, 5),
+		// 15 more offered at 101
+		tick(20, 99, 10, 101, 35, 100, 5),
+	}
+	for _, tick := range ticks {
+		tick.Quote.LastTrade = epoch
+	}
+
+	r, err := New(Config{}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if resp.TotalFilled != 20 || len(resp.Fills) != 1 {
+		t.Errorf("expected response with 20 filled immediately, got %+v", resp)
+	}
+	// The remaining 30 rest at 101 and only take the size added to the ask
+	if r.FilledQty != 35 || r.Position != 35 {
+		t.Errorf("expected 35 bought, got filled=%d position=%d", r.FilledQty, r.Position)
+	}
+	if len(s.fills) != 2 || s.updates != 2 {
+		t.Errorf("expected 2 fills and updates, got %d and %d", len(s.fills), s.updates)
+	}
+	if r.Cash != -35*101 {
+		t.Errorf("expected cash %d, got %d", -35*101, r.Cash)
+	}
+}
+
+func TestSharedLiquidity(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) {
+			tr.Buy(100, 10, sfclient.TypeLimit)
+			tr.Buy(100, 10, sfclient.TypeLimit)
+		},
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 101, 20, 100, 5),
+		// 15 offered through both bids, and a trade of 3 through them
+		tick(10, 99, 10, 98, 15, 97, 3),
+	}
+
+	r, err := New(Config{}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	// The first bid takes 10 of the offer, the second the other 5 and the
+	// whole trade
+	if r.FilledQty != 18 {
+		t.Errorf("expected 18 filled between both bids, got %d", r.FilledQty)
+	}
+}
+
+func TestRecordedFills(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) { tr.Sell(102, 10, sfclient.TypeLimit) },
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 101, 20, 100, 5),
+		// Shows the second of the executions below as the last trade
+		tick(20, 99, 10, 101, 20, 103, 2),
+	}
+
+	execution := func(ms, price, qty, standing, incoming int) *sfclient.FillMessage {
+		return &sfclient.FillMessage{
+			APIResponse: sfclient.APIResponse{OK: true},
+			Price:       price,
+			Filled:      qty,
+			FilledAt:    epoch.Add(time.Duration(ms) * time.Millisecond),
+			StandingID:  standing,
+			IncomingID:  incoming,
+		}
+	}
+	fills := []*sfclient.FillMessage{
+		// Traded through our offer between ticks, reported for both sides
+		execution(5, 104, 4, 7, 8),
+		execution(5, 104, 4, 7, 8),
+		execution(20, 103, 2, 9, 10),
+	}
+
+	r, err := New(Config{}).RunWithFills(s, ticks, fills)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.FilledQty != 6 || r.Position != -6 {
+		t.Errorf("expected 6 sold, got filled=%d position=%d", r.FilledQty, r.Position)
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) { tr.Buy(99, 10, sfclient.TypeLimit) },
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 20, 101, 20, 100, 5),
+		// 15 trade at our price, all of which are ahead of us
+		tick(10, 99, 15, 101, 20, 99, 15),
+		// 10 more: 5 to finish the queue, 5 to us
+		tick(20, 99, 5, 101, 20, 99, 10),
+	}
+
+	r, err := New(Config{QueueAhead: 1}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.FilledQty != 5 {
+		t.Errorf("expected 5 filled behind the queue, got %d", r.FilledQty)
+	}
+	if r.FillRatio != 0.5 {
+		t.Errorf("expected fill ratio 0.5, got %f", r.FillRatio)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) {
+			resp, _ := tr.Buy(101, 10, sfclient.TypeImmediateOrCancel)
+			if resp.TotalFilled != 0 {
+				t.Errorf("order should not fill before arriving")
+			}
+		},
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 101, 20, 100, 5),
+		// Still in flight
+		tick(5, 99, 10, 101, 20, 100, 5),
+		// Arrives after the ask has moved away, so the IOC is killed
+		tick(10, 99, 10, 102, 20, 100, 5),
+	}
+
+	r, err := New(Config{Latency: 10 * time.Millisecond}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.FilledQty != 0 || len(s.fills) != 0 {
+		t.Errorf("expected no fills, got %d", r.FilledQty)
+	}
+}
+
+func TestOutOfOrderTicks(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) { tr.Buy(101, 10, sfclient.TypeImmediateOrCancel) },
+	}}
+
+	// Recorded in the order they arrived: the tick quoted at 10ms, when the
+	// order reaches the exchange, arrived after the one quoted at 12ms
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 101, 20, 100, 5),
+		tick(12, 99, 10, 102, 20, 100, 5),
+		tick(10, 99, 10, 101, 20, 100, 5),
+	}
+
+	r, err := New(Config{Latency: 10 * time.Millisecond}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.FilledQty != 10 {
+		t.Errorf("expected the IOC to fill against the ask quoted at 10ms, got %d", r.FilledQty)
+	}
+	if !ticks[1].Quote.QuoteTime.Equal(epoch.Add(12 * time.Millisecond)) {
+		t.Errorf("expected the ticks given to be left in their order")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	var id int
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) {
+			resp, _ := tr.Sell(105, 10, sfclient.TypeLimit)
+			id = resp.ID
+		},
+		1: func(tr strats.Trader) {
+			resp, err := tr.Cancel(id)
+			if err != nil || resp.TotalFilled != 0 {
+				t.Errorf("unexpected cancel response: %+v %v", resp, err)
+			}
+		},
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 106, 20, 100, 5),
+		tick(10, 99, 10, 106, 20, 100, 5),
+		tick(20, 105, 10, 106, 20, 105, 5),
+	}
+
+	r, err := New(Config{}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.FilledQty != 0 {
+		t.Errorf("cancelled order was filled")
+	}
+}
+
+func TestReportDrawdown(t *testing.T) {
+	s := &scripted{act: map[int]func(strats.Trader){
+		0: func(tr strats.Trader) { tr.Buy(100, 10, sfclient.TypeMarket) },
+	}}
+
+	ticks := []*sfclient.TickMessage{
+		tick(0, 99, 10, 100, 20, 100, 5),
+		tick(10, 104, 10, 106, 20, 105, 5),
+		tick(20, 96, 10, 98, 20, 97, 5),
+		tick(30, 100, 10, 102, 20, 101, 5),
+	}
+
+	r, err := New(Config{TimerInterval: 5 * time.Millisecond}).Run(s, ticks)
+	if err != nil {
+		t.Fatalf("error running backtest: %v", err)
+	}
+
+	if r.PnL != 10 {
+		t.Errorf("expected pnl 10, got %d", r.PnL)
+	}
+	// Peak of +50 at 105 down to -30 at 97
+	if r.MaxDrawdown != 80 {
+		t.Errorf("expected max drawdown 80, got %d", r.MaxDrawdown)
+	}
+	if s.timers != 6 {
+		t.Errorf("expected 6 timer callbacks, got %d", s.timers)
+	}
+}
+
+func TestReadTicks(t *testing.T) {
+	in := `{"ok":true,"quote":{"symbol":"FOOBAR","bid":99,"ask":101}}
+{"ok":true,"quote":{"symbol":"FOOBAR","bid":100,"ask":102}}
+`
+	ticks, err := ReadTicks(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("error reading ticks: %v", err)
+	}
+
+	if len(ticks) != 2 || ticks[1].Quote.Bid != 100 {
+		t.Errorf("unexpected ticks: %+v", ticks)
+	}
+}
@@ -0,0 +1,66 @@
+package backtest
+
+import (
+	"fmt"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Report summarises a backtest. Prices and P&L are in cents.
+type Report struct {
+	Ticks      int
+	Orders     int
+	OrderedQty int
+	FilledQty  int
+	Fills      int
+
+	// FillRatio is the fraction of the ordered quantity that was executed
+	FillRatio float64
+
+	// Position is the number of shares held at the end, negative if short
+	Position int
+	Cash     int
+
+	// PnL is the cash plus the position marked at the last traded price,
+	// or the mid if nothing has traded
+	PnL int
+
+	// MaxDrawdown is the largest fall in marked P&L from a previous peak
+	MaxDrawdown int
+
+	ledger sfclient.Ledger
+	peak   int
+}
+
+func (r *Report) fill(isBuy bool, price, qty int) {
+	r.Fills++
+	r.FilledQty += qty
+	r.ledger.Fill(isBuy, price, qty)
+	r.Position, r.Cash = r.ledger.Position, r.ledger.Cash
+}
+
+// mark revalues the position from q and updates the drawdown.
+func (r *Report) mark(q sfclient.StockState) {
+	r.ledger.Quote(q)
+	r.PnL = r.ledger.PnL()
+	if r.PnL > r.peak {
+		r.peak = r.PnL
+	}
+	if dd := r.peak - r.PnL; dd > r.MaxDrawdown {
+		r.MaxDrawdown = dd
+	}
+}
+
+func (r *Report) finish(q sfclient.StockState) {
+	r.mark(q)
+	if r.OrderedQty > 0 {
+		r.FillRatio = float64(r.FilledQty) / float64(r.OrderedQty)
+	}
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf(
+		"ticks=%d orders=%d fills=%d filled=%d/%d (%.1f%%) position=%d cash=%d pnl=%d maxDrawdown=%d",
+		r.Ticks, r.Orders, r.Fills, r.FilledQty, r.OrderedQty, 100*r.FillRatio,
+		r.Position, r.Cash, r.PnL, r.MaxDrawdown)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ifross89/stockfighter/backtest"
+	"github.com/ifross89/stockfighter/replay"
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/strats"
+)
+
+var (
+	strategy    string
+	ticksFile   string
+	recDir      string
+	recPrefix   string
+	latency     time.Duration
+	queueAhead  float64
+	interval    time.Duration
+	maxExposure int
+	toBuy       int
+	window      int
+)
+
+func init() {
+	flag.StringVar(&strategy, "strategy", "marketmaker", "strategy to run: marketmaker or blockbuyer")
+	flag.StringVar(&ticksFile, "ticks", "", "file of newline delimited tick messages to replay")
+	flag.StringVar(&recDir, "recording", "", "directory of recordings to replay instead of --ticks")
+	flag.StringVar(&recPrefix, "prefix", "", "prefix of the recording to replay, usually VENUE-STOCK")
+	flag.DurationVar(&latency, "latency", 0, "simulated delay before orders and cancels reach the book")
+	flag.Float64Var(&queueAhead, "queue", 1, "fraction of the displayed size assumed ahead of a new resting order")
+	flag.DurationVar(&interval, "interval", 0, "how often the strategy's timer fires, 0 for the interval it runs with live")
+	flag.IntVar(&maxExposure, "maxexposure", 1000, "largest position, long or short, the market maker holds")
+	flag.IntVar(&toBuy, "tobuy", 100000, "shares the block buyer accumulates")
+	flag.IntVar(&window, "window", 20000, "number of quotes the block buyer averages the ask over")
+}
+
+func readTicksFile() ([]*sfclient.TickMessage, error) {
+	f, err := os.Open(ticksFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return backtest.ReadTicks(f)
+}
+
+// readRecording returns the ticks and fills recorded with the prefix.
+func readRecording() ([]*sfclient.TickMessage, []*sfclient.FillMessage, error) {
+	files, err := replay.Glob(recDir, recPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	src := replay.New(files, replay.AsFastAsPossible)
+
+	tickch, err := src.TickListener().Listen()
+	if err != nil {
+		return nil, nil, err
+	}
+	var ticks []*sfclient.TickMessage
+	for msg := range tickch {
+		ticks = append(ticks, msg)
+	}
+
+	fillch, err := src.FillListener().Listen()
+	if err != nil {
+		return nil, nil, err
+	}
+	var fills []*sfclient.FillMessage
+	for msg := range fillch {
+		fills = append(fills, msg)
+	}
+	return ticks, fills, nil
+}
+
+// newStrategy returns the strategy to run and the timer interval it runs
+// with live.
+func newStrategy() (strats.Strategy, time.Duration, error) {
+	switch strategy {
+	case "marketmaker":
+		return strats.NewMarketMaker(maxExposure), 5 * time.Second, nil
+	case "blockbuyer":
+		return strats.NewBlockBuyer(toBuy, sfclient.WindowSize{Count: window}, nil), 100 * time.Millisecond, nil
+	}
+	return nil, 0, fmt.Errorf("unknown strategy %q, expected marketmaker or blockbuyer", strategy)
+}
+
+func main() {
+	flag.Parse()
+
+	s, live, err := newStrategy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if interval == 0 {
+		interval = live
+	}
+
+	var ticks []*sfclient.TickMessage
+	var fills []*sfclient.FillMessage
+	switch {
+	case recDir != "":
+		ticks, fills, err = readRecording()
+	case ticksFile != "":
+		ticks, err = readTicksFile()
+	default:
+		log.Fatal("please provide ticks with --ticks or --recording")
+	}
+	if err != nil {
+		log.Fatalf("could not read ticks: %v", err)
+	}
+
+	engine := backtest.New(backtest.Config{
+		Latency:       latency,
+		QueueAhead:    queueAhead,
+		TimerInterval: interval,
+	})
+
+	report, err := engine.RunWithFills(s, ticks, fills)
+	if err != nil {
+		log.Fatalf("backtest failed: %v", err)
+	}
+
+	fmt.Println(report)
+}
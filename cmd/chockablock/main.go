@@ -2,78 +2,48 @@ package main
 
 import (
 	"flag"
-	"fmt"
 	"log"
+	"time"
 
+	"github.com/ifross89/stockfighter/config"
 	"github.com/ifross89/stockfighter/sfclient"
-	"time"
+	"github.com/ifross89/stockfighter/strats"
 )
 
 var (
-	apiKey  string
-	account string
-	stk   string
-	vnu   string
+	cfg config.Config
 )
 
 func init() {
-	flag.StringVar(&apiKey, "apikey", "", "API key to use for authentication")
-	flag.StringVar(&account, "account", "", "account to do trades with")
-	flag.StringVar(&stk, "stock", "", "stock to trade with")
-	flag.StringVar(&vnu, "venue", "", "venue to trade at")
-}
-
-func checkempty(pairs ...string) error {
-	if len(pairs)%2 != 0 {
-		panic("checkempty must be called with pairs")
-	}
-
-	for i := 0; i < len(pairs); i += 2 {
-		if pairs[i+1] == "" {
-			return fmt.Errorf("parameter %q must be provided", pairs[i])
-		}
-	}
-	return nil
+	cfg.RegisterFlags(flag.CommandLine)
 }
-// WIP
 
 func main() {
 	flag.Parse()
-	stock := sfclient.Symbol(stk)
-	venue := sfclient.Venue(vnu)
-
-	if err := checkempty("apikey", apiKey, "account", account, "stock", stk, "venue", vnu); err != nil {
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if err := cfg.Require(config.APIKey, config.Account, config.Venue, config.Stock); err != nil {
 		log.Fatalf("could not start: %v", err)
 	}
+	stock := sfclient.Symbol(cfg.Stock)
+	venue := sfclient.Venue(cfg.Venue)
 
-	c := sfclient.New(apiKey)
+	c, err := cfg.Client()
+	if err != nil {
+		log.Fatalf("error creating client: %v", err)
+	}
 
-	hub, err := sfclient.NewStockHub(c, account, venue, stock)
+	hub, err := sfclient.NewStockHub(c, c, cfg.Account, venue, stock)
 	if err != nil {
 		log.Fatalf("error creating hub: %v", err)
 	}
 
-	bidasks := sfclient.NewBidAskHistory(20000)
-
-	hub.RegisterComponenets(bidasks)
-
-	stocksToBuy := 100000
-
-	for stocksToBuy >= 0 {
-		avgAsk,_ := bidasks.Avg(20000)
-		_, _, bid, bidSize := bidasks.Current()
-		log.Printf("average ask:%d\tcurrent bid=%dp size%d", avgAsk, bid, bidSize)
+	var runner *strats.Runner
+	buyer := strats.NewBlockBuyer(100000, sfclient.WindowSize{Count: 20000}, func() { runner.Stop() })
+	runner = strats.NewRunner(hub, buyer, 100*time.Millisecond)
 
-		// If current buying price is below average asking price, SELL SELL SELL
-		if bid > avgAsk && bidSize > 0 {
-			log.Printf("executing bid for %d at %d", bidSize, bid)
-			resp, err := hub.BuyIOC(bid, bidSize)
-			if err != nil {
-				log.Printf("error selling: %v", err)
-			}
-			log.Printf("%d filled", resp.Quantity)
-			stocksToBuy -= resp.Quantity
-		}
-		time.Sleep(100*time.Millisecond)
+	if err := runner.Run(); err != nil {
+		log.Fatalf("error running: %v", err)
 	}
 }
@@ -2,49 +2,35 @@ package main
 
 import (
 	"flag"
-	"fmt"
 	"log"
 
+	"github.com/ifross89/stockfighter/config"
 	"github.com/ifross89/stockfighter/sfclient"
 )
 
 var (
-	apiKey  string
-	account string
-	stk   string
-	vnu   string
+	cfg config.Config
 )
 
 func init() {
-	flag.StringVar(&apiKey, "apikey", "", "API key to use for authentication")
-	flag.StringVar(&account, "account", "", "account to do trades with")
-	flag.StringVar(&stk, "stock", "", "stock to trade with")
-	flag.StringVar(&vnu, "venue", "", "venue to trade at")
-}
-
-func checkempty(pairs ...string) error {
-	if len(pairs)%2 != 0 {
-		panic("checkempty must be called with pairs")
-	}
-
-	for i := 0; i < len(pairs); i += 2 {
-		if pairs[i+1] == "" {
-			return fmt.Errorf("parameter %q must be provided", pairs[i])
-		}
-	}
-	return nil
+	cfg.RegisterFlags(flag.CommandLine)
 }
 
 func main() {
 	flag.Parse()
-	stock := sfclient.Symbol(stk)
-	venue := sfclient.Venue(vnu)
-
-	if err := checkempty("apikey", apiKey, "account", account, "stock", stk, "venue", vnu); err != nil {
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if err := cfg.Require(config.APIKey, config.Account, config.Venue, config.Stock); err != nil {
 		log.Fatalf("could not start: %v", err)
 	}
+	stock := sfclient.Symbol(cfg.Stock)
+	venue := sfclient.Venue(cfg.Venue)
 
-	c := sfclient.New(apiKey)
+	c, err := cfg.Client()
+	if err != nil {
+		log.Fatalf("error creating client: %v", err)
+	}
 
 	resp, err := c.StockOrderBook(venue, stock)
 	if err != nil {
@@ -59,7 +45,7 @@ func main() {
 		log.Fatal("no asks on the order book")
 	}
 
-	_, err = c.BuyOrder(account, venue, stock, priceToBuy, 100, sfclient.TypeLimit)
+	_, err = c.BuyOrder(cfg.Account, venue, stock, priceToBuy, 100, sfclient.TypeLimit)
 	if err != nil {
 		log.Fatalf("could not execute buy: %v", err)
 	}
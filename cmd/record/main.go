@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ifross89/stockfighter/config"
+	"github.com/ifross89/stockfighter/recorder"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+var (
+	cfg      config.Config
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	snapshot time.Duration
+)
+
+func init() {
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&dir, "dir", "recordings", "directory to write recordings to")
+	flag.Int64Var(&maxBytes, "maxbytes", 64<<20, "rotate files after this many uncompressed bytes")
+	flag.DurationVar(&maxAge, "maxage", time.Hour, "rotate files after this long")
+	flag.DurationVar(&snapshot, "snapshot", 10*time.Second, "how often to record a full order book snapshot")
+}
+
+func main() {
+	flag.Parse()
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if err := cfg.Require(config.APIKey, config.Account, config.Venue, config.Stock); err != nil {
+		log.Fatalf("could not start: %v", err)
+	}
+	stock := sfclient.Symbol(cfg.Stock)
+	venue := sfclient.Venue(cfg.Venue)
+
+	c, err := cfg.Client()
+	if err != nil {
+		log.Fatalf("error creating client: %v", err)
+	}
+
+	hub, err := sfclient.NewStockHub(c, c, cfg.Account, venue, stock)
+	if err != nil {
+		log.Fatalf("error creating hub: %v", err)
+	}
+
+	rec, err := recorder.New(recorder.Config{
+		Dir:              dir,
+		MaxBytes:         maxBytes,
+		MaxAge:           maxAge,
+		SnapshotInterval: snapshot,
+	})
+	if err != nil {
+		log.Fatalf("error creating recorder: %v", err)
+	}
+
+	hub.RegisterComponenets(rec)
+	log.Printf("recording %s on %s to %s", stock, venue, dir)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	<-sigs
+
+	if err := rec.Close(); err != nil {
+		log.Fatalf("error closing recording: %v", err)
+	}
+}
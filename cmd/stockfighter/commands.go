@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ifross89/stockfighter/config"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+var commands []command
+
+func init() {
+	// Assigned here as the commands refer to usage, which reads commands
+	commands = []command{
+		{name: "heartbeat", help: "check the API, and the venue if one is set", nargs: exactly(0), run: heartbeat},
+		{name: "stocks", help: "list the stocks traded on the venue", needs: []string{config.Venue}, nargs: exactly(0), run: stocks},
+		{name: "book", help: "show the order book", needs: []string{config.Venue, config.Stock}, nargs: exactly(0), run: book},
+		{name: "quote", help: "show the latest quote", needs: []string{config.Venue, config.Stock}, nargs: exactly(0), run: quote},
+		{name: "buy", args: "PRICE QTY [TYPE]", help: "buy the stock; TYPE is limit (default), market, fok or ioc",
+			needs: []string{config.Account, config.Venue, config.Stock}, nargs: between(2, 3), run: order("buy")},
+		{name: "sell", args: "PRICE QTY [TYPE]", help: "sell the stock",
+			needs: []string{config.Account, config.Venue, config.Stock}, nargs: between(2, 3), run: order("sell")},
+		{name: "cancel", args: "ID", help: "cancel an order", needs: []string{config.Venue, config.Stock}, nargs: exactly(1), run: cancel},
+		{name: "status", args: "ID", help: "show an order", needs: []string{config.Venue, config.Stock}, nargs: exactly(1), run: status},
+		{name: "orders", help: "list our orders in the stock, or on the venue if no stock is set",
+			needs: []string{config.Account, config.Venue}, nargs: exactly(0), run: orders},
+		{name: "tail", args: "ticks|fills", help: "print ticks or fills as they arrive, for the stock or whole venue",
+			needs: []string{config.Account, config.Venue}, nargs: exactly(1), run: tail},
+	}
+}
+
+// print writes v as JSON, or as a table drawn by table.
+func (e *env) print(v any, table func(w *tabwriter.Writer)) error {
+	if e.json {
+		enc := json.NewEncoder(e.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func heartbeat(e *env, args []string) error {
+	hr, err := e.client.Heartbeat()
+	if err != nil {
+		return err
+	}
+	if cfg.Venue == "" {
+		return e.print(hr, func(w *tabwriter.Writer) { fmt.Fprintln(w, "api\tok") })
+	}
+
+	vr, err := e.client.VenueHeartbeat(e.venue())
+	if err != nil {
+		return err
+	}
+	return e.print(vr, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "api\tok")
+		fmt.Fprintf(w, "%s\tok\n", vr.Venue)
+	})
+}
+
+func stocks(e *env, args []string) error {
+	sr, err := e.client.VenueStocks(e.venue())
+	if err != nil {
+		return err
+	}
+	return e.print(sr, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "SYMBOL\tNAME")
+		for _, s := range sr.Symbols {
+			fmt.Fprintf(w, "%s\t%s\n", s.Symbol, s.Name)
+		}
+	})
+}
+
+func book(e *env, args []string) error {
+	br, err := e.client.StockOrderBook(e.venue(), e.stock())
+	if err != nil {
+		return err
+	}
+	return e.print(br, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "BID QTY\tBID\tASK\tASK QTY")
+		for i := 0; i < max(len(br.Bids), len(br.Asks)); i++ {
+			var bidQty, bid, ask, askQty string
+			if i < len(br.Bids) {
+				bidQty, bid = strconv.Itoa(br.Bids[i].Quantity), sfclient.FormatPrice(br.Bids[i].Price)
+			}
+			if i < len(br.Asks) {
+				ask, askQty = sfclient.FormatPrice(br.Asks[i].Price), strconv.Itoa(br.Asks[i].Quantity)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", bidQty, bid, ask, askQty)
+		}
+	})
+}
+
+func quote(e *env, args []string) error {
+	qr, err := e.client.Quote(e.venue(), e.stock())
+	if err != nil {
+		return err
+	}
+	q := qr.StockState
+	return e.print(qr, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "bid\t%s\t%d\tdepth %d\n", price(q.Bid), q.BidSize, q.BidDepth)
+		fmt.Fprintf(w, "ask\t%s\t%d\tdepth %d\n", price(q.Ask), q.AskSize, q.AskDepth)
+		fmt.Fprintf(w, "last\t%s\t%d\t%s\n", price(q.Last), q.LastSize, q.LastTrade.Local().Format(time.TimeOnly))
+	})
+}
+
+// price formats a price that is 0 when there is none.
+func price(cents int) string {
+	if cents == 0 {
+		return "-"
+	}
+	return sfclient.FormatPrice(cents)
+}
+
+func order(direction string) func(e *env, args []string) error {
+	return func(e *env, args []string) error {
+		p, err := sfclient.ParsePrice(args[0])
+		if err != nil {
+			return err
+		}
+		qty, err := strconv.Atoi(args[1])
+		if err != nil || qty <= 0 {
+			return fmt.Errorf("invalid quantity %q", args[1])
+		}
+		typ := sfclient.TypeLimit
+		if len(args) == 3 {
+			if typ, err = sfclient.ParseOrderType(args[2]); err != nil {
+				return err
+			}
+		}
+
+		place := e.client.SellOrder
+		if direction == "buy" {
+			place = e.client.BuyOrder
+		}
+		or, err := place(cfg.Account, e.venue(), e.stock(), p, qty, typ)
+		if err != nil {
+			return err
+		}
+		return e.print(or, func(w *tabwriter.Writer) {
+			printOrders(w, []sfclient.OrderState{{
+				ID: or.ID, Direction: or.Direction, OrderType: sfclient.OrderType(or.OrderType), Price: or.Price,
+				Quantity: or.Quantity, TotalFilled: or.TotalFilled, Open: or.Open, Timestamp: or.Timestamp,
+			}})
+		})
+	}
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid order id %q", s)
+	}
+	return id, nil
+}
+
+func cancel(e *env, args []string) error {
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	cr, err := e.client.CancelOrder(e.venue(), e.stock(), id)
+	if err != nil {
+		return err
+	}
+	return e.print(cr, func(w *tabwriter.Writer) { printOrders(w, []sfclient.OrderState{cr.OrderState}) })
+}
+
+func status(e *env, args []string) error {
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	sr, err := e.client.OrderStatus(e.venue(), e.stock(), id)
+	if err != nil {
+		return err
+	}
+	return e.print(sr, func(w *tabwriter.Writer) { printOrders(w, []sfclient.OrderState{sr.OrderState}) })
+}
+
+func orders(e *env, args []string) error {
+	var mr *sfclient.MultiStatusResponse
+	var err error
+	if cfg.Stock == "" {
+		mr, err = e.client.VenueOrdersStatus(cfg.Account, e.venue())
+	} else {
+		mr, err = e.client.StockOrdersStatus(cfg.Account, e.venue(), e.stock())
+	}
+	if err != nil {
+		return err
+	}
+	return e.print(mr, func(w *tabwriter.Writer) { printOrders(w, mr.Orders) })
+}
+
+func printOrders(w *tabwriter.Writer, orders []sfclient.OrderState) {
+	fmt.Fprintln(w, "ID\tSIDE\tTYPE\tPRICE\tLEFT\tFILLED\tOPEN\tTIME")
+	for _, o := range orders {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%t\t%s\n", o.ID, o.Direction, o.OrderType, sfclient.FormatPrice(o.Price),
+			o.Quantity, o.TotalFilled, o.Open, o.Timestamp.Local().Format(time.DateTime))
+	}
+}
+
+// tail prints messages from a websocket until interrupted, one line each.
+func tail(e *env, args []string) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	enc := json.NewEncoder(e.out)
+	switch args[0] {
+	case "ticks":
+		var tl *sfclient.TickListener
+		var err error
+		if cfg.Stock == "" {
+			tl, err = e.client.VenueTicker(cfg.Account, e.venue())
+		} else {
+			tl, err = e.client.StockTicker(cfg.Account, e.venue(), e.stock())
+		}
+		if err != nil {
+			return err
+		}
+		ticks, err := tl.Listen()
+		if err != nil {
+			return err
+		}
+		defer tl.Close()
+
+		for {
+			select {
+			case <-sigs:
+				return nil
+			case msg, ok := <-ticks:
+				if !ok {
+					return fmt.Errorf("tickertape closed")
+				}
+				if e.json {
+					enc.Encode(msg)
+					continue
+				}
+				q := msg.Quote
+				fmt.Fprintf(e.out, "%s %s bid %s x %d  ask %s x %d  last %s x %d\n", q.QuoteTime.Local().Format(time.TimeOnly),
+					q.Symbol, price(q.Bid), q.BidSize, price(q.Ask), q.AskSize, price(q.Last), q.LastSize)
+			}
+		}
+	case "fills":
+		var fl *sfclient.FillListener
+		var err error
+		if cfg.Stock == "" {
+			fl, err = e.client.VenueFills(cfg.Account, e.venue())
+		} else {
+			fl, err = e.client.StockFills(cfg.Account, e.venue(), e.stock())
+		}
+		if err != nil {
+			return err
+		}
+		fills, err := fl.Listen()
+		if err != nil {
+			return err
+		}
+		defer fl.Close()
+
+		for {
+			select {
+			case <-sigs:
+				return nil
+			case msg, ok := <-fills:
+				if !ok {
+					return fmt.Errorf("executions closed")
+				}
+				if e.json {
+					enc.Encode(msg)
+					continue
+				}
+				fmt.Fprintf(e.out, "%s %s order %d %s %d @ %s, %d left\n", msg.FilledAt.Local().Format(time.TimeOnly),
+					msg.Symbol, msg.Order.ID, msg.Order.Direction, msg.Filled, sfclient.FormatPrice(msg.Price), msg.Order.Quantity)
+			}
+		}
+	}
+	return fmt.Errorf("expected ticks or fills, got %q", args[0])
+}
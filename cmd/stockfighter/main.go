@@ -0,0 +1,118 @@
+// Command stockfighter calls the Stockfighter API from the shell.
+//
+//	stockfighter [flags] command [args]
+//
+// Settings such as the API key and account are read from flags, the
+// environment or a config file, as described in package config. Output is a
+// table by default, or the API's JSON with -format json.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ifross89/stockfighter/config"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+var (
+	cfg    config.Config
+	format string
+)
+
+func init() {
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&format, "format", "table", "output format: table or json")
+	flag.Usage = usage
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: stockfighter [flags] command [args]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-26s %s\n", strings.TrimSpace(c.name+" "+c.args), c.help)
+	}
+	fmt.Fprintf(out, "\nprices are in dollars, such as 100.25\n\nflags:\n")
+	flag.PrintDefaults()
+}
+
+// command is a subcommand. It needs the config settings listed and the
+// number of arguments described by args.
+type command struct {
+	name  string
+	args  string
+	help  string
+	needs []string
+	nargs func(n int) bool
+	run   func(e *env, args []string) error
+}
+
+func exactly(n int) func(int) bool {
+	return func(got int) bool { return got == n }
+}
+
+func between(lo, hi int) func(int) bool {
+	return func(got int) bool { return got >= lo && got <= hi }
+}
+
+// env is what commands run with.
+type env struct {
+	client *sfclient.Client
+	out    io.Writer
+	json   bool
+}
+
+func (e *env) venue() sfclient.Venue  { return sfclient.Venue(cfg.Venue) }
+func (e *env) stock() sfclient.Symbol { return sfclient.Symbol(cfg.Stock) }
+
+func find(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd := find(flag.Arg(0))
+	if cmd == nil {
+		log.Printf("unknown command %q", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	args := flag.Args()[1:]
+	if !cmd.nargs(len(args)) {
+		log.Fatalf("usage: stockfighter %s %s", cmd.name, cmd.args)
+	}
+	if format != "table" && format != "json" {
+		log.Fatalf("unknown format %q, expected table or json", format)
+	}
+
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if err := cfg.Require(cmd.needs...); err != nil {
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+	c, err := cfg.Client()
+	if err != nil {
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+
+	e := &env{client: c, out: os.Stdout, json: format == "json"}
+	if err := cmd.run(e, args); err != nil {
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+}
@@ -0,0 +1,224 @@
+// Command tui is a full-screen terminal view of a stock for trading by
+// hand: the top of book, a depth ladder, recent trades, and our open orders
+// and fills, with keys to place and cancel orders.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ifross89/stockfighter/config"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+var (
+	cfg          config.Config
+	logFile      string
+	bookInterval time.Duration
+	cancelOnExit bool
+)
+
+func init() {
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&logFile, "log", "", "file to log to, as the screen is in use")
+	flag.DurationVar(&bookInterval, "book", time.Second, "how often to fetch the order book and our orders")
+	flag.BoolVar(&cancelOnExit, "cancel", true, "cancel open orders on exit")
+}
+
+func newLogger() (*slog.Logger, error) {
+	if logFile == "" {
+		return slog.New(slog.NewTextHandler(io.Discard, nil)), nil
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewTextHandler(f, nil)), nil
+}
+
+func main() {
+	flag.Parse()
+
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if err := cfg.Require(config.APIKey, config.Account, config.Venue, config.Stock); err != nil {
+		log.Fatalf("could not start: %v", err)
+	}
+
+	if !isTerminal() {
+		log.Fatal("stdin is not a terminal")
+	}
+
+	logger, err := newLogger()
+	if err != nil {
+		log.Fatalf("error opening log: %v", err)
+	}
+
+	c, err := cfg.Client(sfclient.WithLogger(logger))
+	if err != nil {
+		log.Fatalf("error creating client: %v", err)
+	}
+	hub, err := sfclient.NewStockHub(c, c, cfg.Account, sfclient.Venue(cfg.Venue), sfclient.Symbol(cfg.Stock))
+	if err != nil {
+		log.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	restore, err := makeRaw()
+	if err != nil {
+		log.Fatalf("error setting up terminal: %v", err)
+	}
+	// Switch to the alternate screen and hide the cursor until we exit
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	m := newModel(hub)
+	run(m, hub)
+
+	if cancelOnExit {
+		for _, o := range m.openOrders() {
+			if _, err := hub.Cancel(o.ID); err != nil {
+				logger.Error("error cancelling order on exit", sfclient.LogOrderID, o.ID, "err", err)
+			}
+		}
+	}
+}
+
+// run processes market data and keypresses until the user quits.
+func run(m *model, hub *sfclient.StockHub) {
+	ticks := make(chan *sfclient.TickMessage, 100)
+	fills := make(chan *sfclient.FillMessage, 100)
+	hub.RegisterToTick(ticks)
+	hub.RegisterToFills(fills)
+
+	keys := make(chan []byte)
+	go readKeys(keys)
+
+	// Polling stops when the UI does
+	done := make(chan struct{})
+	defer close(done)
+
+	books := make(chan *sfclient.StockOrderBookResponse, 1)
+	orders := make(chan []sfclient.OrderState, 1)
+	go poll(hub, books, orders, done)
+
+	// Redraw at most every frame, rather than for every message
+	frame := time.NewTicker(100 * time.Millisecond)
+	defer frame.Stop()
+	dirty := true
+
+	// Finding the size of the terminal runs stty, so only check for it
+	// being resized every second
+	resize := time.NewTicker(time.Second)
+	defer resize.Stop()
+	width, height := termSize()
+
+	for {
+		select {
+		case msg := <-ticks:
+			if msg.OK {
+				m.tick(msg.Quote)
+			}
+		case msg := <-fills:
+			if msg.OK {
+				m.fill(msg)
+			}
+		case b := <-books:
+			m.book = b
+		case o := <-orders:
+			m.setOrders(o)
+		case in := <-keys:
+			// Ignore escape sequences such as arrow keys, but not a lone escape
+			if len(in) > 1 && in[0] == 0x1b {
+				continue
+			}
+			for _, b := range in {
+				if m.key(b) {
+					return
+				}
+			}
+			draw(m, width, height)
+			dirty = false
+			continue
+		case <-resize.C:
+			w, h := termSize()
+			if w == width && h == height {
+				continue
+			}
+			width, height = w, h
+		case <-frame.C:
+			if dirty {
+				draw(m, width, height)
+				dirty = false
+			}
+			continue
+		}
+		dirty = true
+	}
+}
+
+// poll fetches the order book and our orders every bookInterval until done
+// is closed.
+func poll(hub *sfclient.StockHub, books chan<- *sfclient.StockOrderBookResponse, orders chan<- []sfclient.OrderState, done <-chan struct{}) {
+	for {
+		if b, err := hub.OrderBook(); err != nil {
+			hub.Logger().Warn("error fetching order book", "err", err)
+		} else {
+			select {
+			case books <- b:
+			case <-done:
+				return
+			}
+		}
+		if o, err := hub.Orders(); err != nil {
+			hub.Logger().Warn("error fetching orders", "err", err)
+		} else {
+			select {
+			case orders <- o.Orders:
+			case <-done:
+				return
+			}
+		}
+
+		select {
+		case <-time.After(bookInterval):
+		case <-done:
+			return
+		}
+	}
+}
+
+func readKeys(keys chan<- []byte) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		keys <- append([]byte(nil), buf[:n]...)
+	}
+}
+
+func draw(m *model, width, height int) {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	for i, line := range m.render(width, height) {
+		if i > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\x1b[K")
+	}
+	sb.WriteString("\x1b[J")
+	os.Stdout.WriteString(sb.String())
+}
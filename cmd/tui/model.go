@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+const (
+	maxTrades = 100
+	maxFills  = 100
+)
+
+type trade struct {
+	at         time.Time
+	price, qty int
+}
+
+// model is everything shown on screen. It is only touched by the event
+// loop, so needs no locking.
+type model struct {
+	hub *sfclient.StockHub
+
+	quote  sfclient.StockState
+	book   *sfclient.StockOrderBookResponse
+	trades []trade // newest first
+	fills  []*sfclient.FillMessage
+	orders map[int]*sfclient.OrderResponse
+
+	ledger sfclient.Ledger
+
+	// status is the outcome of the last action, shown above the prompt
+	status string
+	prompt *prompt
+}
+
+func newModel(hub *sfclient.StockHub) *model {
+	return &model{
+		hub:    hub,
+		book:   &sfclient.StockOrderBookResponse{},
+		orders: map[int]*sfclient.OrderResponse{},
+		status: "ready",
+	}
+}
+
+func (m *model) tick(q sfclient.StockState) {
+	prev := m.quote
+	m.quote = q
+
+	if q.LastSize > 0 && !q.LastTrade.IsZero() && !q.LastTrade.Equal(prev.LastTrade) {
+		m.trades = append([]trade{{at: q.LastTrade, price: q.Last, qty: q.LastSize}}, m.trades[:min(len(m.trades), maxTrades-1)]...)
+	}
+
+	m.ledger.Quote(q)
+}
+
+func (m *model) fill(msg *sfclient.FillMessage) {
+	m.ledger.Fill(msg.Order.Direction == "buy", msg.Price, msg.Filled)
+	m.fills = append([]*sfclient.FillMessage{msg}, m.fills[:min(len(m.fills), maxFills-1)]...)
+
+	o := msg.Order
+	m.track(&o)
+}
+
+// track records the latest state of one of our orders.
+func (m *model) track(o *sfclient.OrderResponse) {
+	if o.Open {
+		m.orders[o.ID] = o
+	} else {
+		delete(m.orders, o.ID)
+	}
+}
+
+// setOrders replaces the open orders with those fetched from the exchange.
+func (m *model) setOrders(orders []sfclient.OrderState) {
+	m.orders = map[int]*sfclient.OrderResponse{}
+	for _, o := range orders {
+		if !o.Open {
+			continue
+		}
+		m.orders[o.ID] = &sfclient.OrderResponse{
+			Direction:        o.Direction,
+			OriginalQuantity: o.OriginalQuantity,
+			Quantity:         o.Quantity,
+			Price:            o.Price,
+			OrderType:        string(o.OrderType),
+			ID:               o.ID,
+			TotalFilled:      o.TotalFilled,
+			Open:             o.Open,
+		}
+	}
+}
+
+// openOrders returns the open orders, newest first.
+func (m *model) openOrders() []*sfclient.OrderResponse {
+	ret := make([]*sfclient.OrderResponse, 0, len(m.orders))
+	for _, o := range m.orders {
+		ret = append(ret, o)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID > ret[j].ID })
+	return ret
+}
+
+// order places an order for qty at price, entered in dollars.
+func (m *model) order(isBuy bool, typ sfclient.OrderType, args string) {
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		m.status = "expected a price and quantity, such as 100.25 10"
+		return
+	}
+	price, err := sfclient.ParsePrice(fields[0])
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	qty, err := strconv.Atoi(fields[1])
+	if err != nil || qty <= 0 {
+		m.status = fmt.Sprintf("invalid quantity %q", fields[1])
+		return
+	}
+
+	side, place := "sell", m.hub.Sell
+	if isBuy {
+		side, place = "buy", m.hub.Buy
+	}
+	resp, err := place(price, qty, typ)
+	if err != nil {
+		m.status = fmt.Sprintf("error placing %s: %v", side, err)
+		return
+	}
+	m.track(resp)
+	m.status = fmt.Sprintf("%s %s %d @ %s: order %d, %d filled", typ, side, qty, sfclient.FormatPrice(price), resp.ID, resp.TotalFilled)
+}
+
+// cancel cancels the order whose id is given in args.
+func (m *model) cancel(args string) {
+	id, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil {
+		m.status = fmt.Sprintf("invalid order id %q", args)
+		return
+	}
+	m.status = m.cancelOrder(id)
+}
+
+// cancelAll cancels every open order.
+func (m *model) cancelAll() {
+	open := m.openOrders()
+	if len(open) == 0 {
+		m.status = "no open orders"
+		return
+	}
+	var failed []string
+	for _, o := range open {
+		if _, err := m.hub.Cancel(o.ID); err != nil {
+			failed = append(failed, strconv.Itoa(o.ID))
+			continue
+		}
+		delete(m.orders, o.ID)
+	}
+	m.status = fmt.Sprintf("cancelled %d orders", len(open)-len(failed))
+	if len(failed) > 0 {
+		m.status += ", failed to cancel " + strings.Join(failed, " ")
+	}
+}
+
+func (m *model) cancelOrder(id int) string {
+	resp, err := m.hub.Cancel(id)
+	if err != nil {
+		return fmt.Sprintf("error cancelling %d: %v", id, err)
+	}
+	delete(m.orders, id)
+	return fmt.Sprintf("cancelled %d, %d filled", id, resp.TotalFilled)
+}
+
+// prompt collects a line of input for an action.
+type prompt struct {
+	label  string
+	input  []byte
+	submit func(string)
+}
+
+// key handles a keypress. It reports whether the program should exit.
+func (m *model) key(b byte) (quit bool) {
+	if p := m.prompt; p != nil {
+		switch b {
+		case '\r', '\n':
+			m.prompt = nil
+			p.submit(string(p.input))
+		case 0x1b: // escape
+			m.prompt = nil
+			m.status = "cancelled"
+		case 0x7f, 0x08: // backspace
+			if len(p.input) > 0 {
+				p.input = p.input[:len(p.input)-1]
+			}
+		case 0x03: // ctrl-c
+			return true
+		default:
+			if b >= ' ' && b < 0x7f {
+				p.input = append(p.input, b)
+			}
+		}
+		return false
+	}
+
+	ask := func(label string, submit func(string)) {
+		m.prompt = &prompt{label: label, submit: submit}
+	}
+	switch b {
+	case 'q', 0x03:
+		return true
+	case 'b':
+		ask("limit buy (price qty)", func(s string) { m.order(true, sfclient.TypeLimit, s) })
+	case 's':
+		ask("limit sell (price qty)", func(s string) { m.order(false, sfclient.TypeLimit, s) })
+	case 'B':
+		ask("IOC buy (price qty)", func(s string) { m.order(true, sfclient.TypeImmediateOrCancel, s) })
+	case 'S':
+		ask("IOC sell (price qty)", func(s string) { m.order(false, sfclient.TypeImmediateOrCancel, s) })
+	case 'c':
+		ask("cancel order id", m.cancel)
+	case 'C':
+		m.cancelAll()
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+const (
+	green = "\x1b[32m"
+	red   = "\x1b[31m"
+	dim   = "\x1b[2m"
+	bold  = "\x1b[1m"
+	reset = "\x1b[0m"
+)
+
+const help = "b/s limit buy/sell  B/S IOC buy/sell  c cancel  C cancel all  q quit"
+
+// cell is a line of a column, drawn in color.
+type cell struct {
+	text  string
+	color string
+}
+
+// column is a block of lines drawn side by side with others.
+type column struct {
+	width int
+	cells []cell
+}
+
+// render draws the model as height lines of at most width characters.
+func (m *model) render(width, height int) []string {
+	var lines []string
+	add := func(s string, color string) {
+		lines = append(lines, paint(fit(s, width), color))
+	}
+
+	add(fmt.Sprintf("%s @ %s  account %s  position %d  cash %s  p&l %s",
+		m.hub.Stock(), m.hub.Venue(), m.hub.Account(), m.ledger.Position, sfclient.FormatPrice(m.ledger.Cash), sfclient.FormatPrice(m.ledger.PnL())), bold)
+	q := m.quote
+	add(fmt.Sprintf("bid %s x %d   ask %s x %d   last %s x %d   depth %d / %d",
+		price(q.Bid), q.BidSize, price(q.Ask), q.AskSize, price(q.Last), q.LastSize, q.BidDepth, q.AskDepth), "")
+	add("", "")
+
+	// Leave room for the headers above and the status, prompt and help below
+	rows := max(0, height-len(lines)-3)
+	cols := []column{
+		{width: 20, cells: m.ladder(rows)},
+		{width: 24, cells: m.tradeCells(rows)},
+		{width: max(0, width-46), cells: m.orderCells(rows)},
+	}
+	for i := 0; i < rows; i++ {
+		var sb strings.Builder
+		for j, c := range cols {
+			if j > 0 {
+				sb.WriteString(" ")
+			}
+			var cl cell
+			if i < len(c.cells) {
+				cl = c.cells[i]
+			}
+			sb.WriteString(paint(pad(fit(cl.text, c.width), c.width), cl.color))
+		}
+		lines = append(lines, sb.String())
+	}
+
+	add(m.status, dim)
+	if p := m.prompt; p != nil {
+		add(p.label+": "+string(p.input), bold)
+	} else {
+		add("", "")
+	}
+	add(help, dim)
+	return lines
+}
+
+// ladder shows the asks above the bids, best prices meeting in the middle.
+func (m *model) ladder(rows int) []cell {
+	cells := []cell{{text: fmt.Sprintf("%10s %8s", "price", "qty"), color: dim}}
+	levels := max(0, (rows-1)/2)
+
+	asks := m.book.Asks[:min(levels, len(m.book.Asks))]
+	for i := levels - 1; i >= 0; i-- {
+		if i >= len(asks) {
+			cells = append(cells, cell{})
+			continue
+		}
+		cells = append(cells, cell{text: fmt.Sprintf("%10s %8d", sfclient.FormatPrice(asks[i].Price), asks[i].Quantity), color: red})
+	}
+	for _, b := range m.book.Bids[:min(levels, len(m.book.Bids))] {
+		cells = append(cells, cell{text: fmt.Sprintf("%10s %8d", sfclient.FormatPrice(b.Price), b.Quantity), color: green})
+	}
+	return cells
+}
+
+func (m *model) tradeCells(rows int) []cell {
+	cells := []cell{{text: fmt.Sprintf("%-8s %8s %6s", "trades", "price", "qty"), color: dim}}
+	for _, t := range m.trades[:min(len(m.trades), max(0, rows-1))] {
+		cells = append(cells, cell{text: fmt.Sprintf("%-8s %8s %6d", t.at.Local().Format("15:04:05"), sfclient.FormatPrice(t.price), t.qty)})
+	}
+	return cells
+}
+
+// orderCells lists our open orders and, below them, our fills.
+func (m *model) orderCells(rows int) []cell {
+	cells := []cell{{text: fmt.Sprintf("%-8s %-4s %-6s %8s %11s", "order", "side", "type", "price", "left/filled"), color: dim}}
+	for _, o := range m.openOrders() {
+		cells = append(cells, cell{
+			text:  fmt.Sprintf("%-8d %-4s %-6s %8s %5d/%-5d", o.ID, o.Direction, shortTypes[sfclient.OrderType(o.OrderType)], sfclient.FormatPrice(o.Price), o.Quantity, o.TotalFilled),
+			color: sideColor(o.Direction),
+		})
+	}
+
+	cells = append(cells, cell{}, cell{text: fmt.Sprintf("%-8s %-4s %-12s %8s %6s", "fill", "side", "time", "price", "qty"), color: dim})
+	for _, f := range m.fills {
+		cells = append(cells, cell{
+			text:  fmt.Sprintf("%-8d %-4s %-12s %8s %6d", f.Order.ID, f.Order.Direction, f.FilledAt.Local().Format("15:04:05.000"), sfclient.FormatPrice(f.Price), f.Filled),
+			color: sideColor(f.Order.Direction),
+		})
+	}
+	return cells[:min(len(cells), rows)]
+}
+
+var shortTypes = map[sfclient.OrderType]string{
+	sfclient.TypeLimit:             "limit",
+	sfclient.TypeMarket:            "market",
+	sfclient.TypeFillOrKill:        "fok",
+	sfclient.TypeImmediateOrCancel: "ioc",
+}
+
+func sideColor(direction string) string {
+	if direction == "buy" {
+		return green
+	}
+	return red
+}
+
+// price formats a price that is 0 when there is none.
+func price(cents int) string {
+	if cents == 0 {
+		return "-"
+	}
+	return sfclient.FormatPrice(cents)
+}
+
+func fit(s string, width int) string {
+	if len(s) > width {
+		return s[:width]
+	}
+	return s
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-len(s)))
+}
+
+func paint(s, color string) string {
+	if color == "" || s == "" {
+		return s
+	}
+	return color + s + reset
+}
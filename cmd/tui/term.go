@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The terminal is set up with stty rather than a terminal package, so the
+// command needs nothing beyond the standard library on a Unix system.
+
+// stty runs stty on the terminal attached to stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// isTerminal reports whether stdin is a terminal.
+func isTerminal() bool {
+	_, err := stty("-g")
+	return err == nil
+}
+
+// makeRaw puts the terminal into raw mode, so keys are read as they are
+// pressed and not echoed. restore puts back the previous settings.
+func makeRaw() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(state) }, nil
+}
+
+// termSize returns the width and height of the terminal, or 80x24 if they
+// cannot be found.
+func termSize() (width, height int) {
+	out, err := stty("size")
+	if err == nil {
+		if _, err := fmt.Sscan(out, &height, &width); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+	}
+	return 80, 24
+}
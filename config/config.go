@@ -0,0 +1,247 @@
+// Package config loads the settings every command needs: the API key, the
+// trading account, and the venue and stock to trade. Each setting is taken
+// from the first of these that provides it:
+//
+//  1. a command line flag, such as -account
+//  2. an environment variable, such as STOCKFIGHTER_ACCOUNT
+//  3. the section of the config file for the profile in use
+//  4. the top of the config file, before any section
+//
+// The config file holds one "name = value" setting per line, with blank
+// lines and lines starting with # ignored. It is read from -config,
+// $STOCKFIGHTER_CONFIG or stockfighter/config in the user's config
+// directory, and keeping the API key there keeps it out of shell history.
+//
+// A line such as "[chock_a_block]" starts the section for a profile, which
+// is chosen with -profile or $STOCKFIGHTER_PROFILE. Each level runs on its
+// own account, venue and stock, so a file might hold the API key at the top
+// and a profile for each level:
+//
+//	apikey = 0123abcd
+//
+//	[first_steps]
+//	account = EXB123456
+//	venue = TESTEX
+//	stock = FOOBAR
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Names of the settings, as used for flags, in the config file and with
+// Require.
+const (
+	APIKey  = "apikey"
+	Account = "account"
+	Venue   = "venue"
+	Stock   = "stock"
+)
+
+// envPrefix is prepended to the upper-cased name of a setting to give its
+// environment variable.
+const envPrefix = "STOCKFIGHTER_"
+
+// Config holds the settings shared by commands.
+type Config struct {
+	APIKey  string
+	Account string
+	Venue   string
+	Stock   string
+
+	// File is the config file to read. If empty, $STOCKFIGHTER_CONFIG or
+	// the default location is used.
+	File string
+
+	// Profile is the section of the config file to read settings from
+	// before those at the top. If empty, $STOCKFIGHTER_PROFILE is used.
+	Profile string
+}
+
+type setting struct {
+	name  string
+	value *string
+	usage string
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{APIKey, &c.APIKey, "API key to use for authentication; prefer $STOCKFIGHTER_APIKEY or the config file"},
+		{Account, &c.Account, "account to trade with"},
+		{Venue, &c.Venue, "venue to trade at"},
+		{Stock, &c.Stock, "stock to trade"},
+	}
+}
+
+// RegisterFlags adds a flag for each setting, and -config for the file, to
+// flags.
+func (c *Config) RegisterFlags(flags *flag.FlagSet) {
+	for _, s := range c.settings() {
+		flags.StringVar(s.value, s.name, "", s.usage)
+	}
+	flags.StringVar(&c.File, "config", "", "config file to read settings from")
+	flags.StringVar(&c.Profile, "profile", "", "section of the config file to use, such as the level being played")
+}
+
+// envName returns the environment variable of a setting.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(name)
+}
+
+// path returns the config file to read and whether it was asked for, so
+// must exist.
+func (c *Config) path() (string, bool, error) {
+	if c.File != "" {
+		return c.File, true, nil
+	}
+	if p := os.Getenv(envName("config")); p != "" {
+		return p, true, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false, err
+	}
+	return filepath.Join(dir, "stockfighter", "config"), false, nil
+}
+
+// Load fills the settings not already set, by flags or otherwise, from the
+// environment and then the config file. A missing config file is only an
+// error if one was named, and a profile must have a section in the file.
+func (c *Config) Load() error {
+	for _, s := range c.settings() {
+		if *s.value == "" {
+			*s.value = os.Getenv(envName(s.name))
+		}
+	}
+	if c.Profile == "" {
+		c.Profile = os.Getenv(envName("profile"))
+	}
+
+	path, named, err := c.path()
+	if err != nil {
+		// Without a home directory there is no default file to read
+		return c.checkProfile(nil)
+	}
+	sections, err := readFile(path)
+	if err != nil {
+		if !named && errors.Is(err, fs.ErrNotExist) {
+			return c.checkProfile(nil)
+		}
+		return err
+	}
+	c.File = path
+	if err := c.checkProfile(sections); err != nil {
+		return err
+	}
+
+	known := map[string]*string{}
+	for _, s := range c.settings() {
+		known[s.name] = s.value
+	}
+	for _, section := range sections {
+		for name := range section {
+			if _, ok := known[name]; !ok {
+				return fmt.Errorf("%s: unknown setting %q", path, name)
+			}
+		}
+	}
+	for _, section := range []string{c.Profile, ""} {
+		for name, v := range sections[section] {
+			if p := known[name]; *p == "" {
+				*p = v
+			}
+		}
+	}
+	return nil
+}
+
+// checkProfile returns an error if the profile in use has no section in the
+// config file.
+func (c *Config) checkProfile(sections map[string]map[string]string) error {
+	if c.Profile == "" {
+		return nil
+	}
+	if _, ok := sections[c.Profile]; !ok {
+		if c.File == "" {
+			return fmt.Errorf("profile %q given without a config file", c.Profile)
+		}
+		return fmt.Errorf("%s: no section for profile %q", c.File, c.Profile)
+	}
+	return nil
+}
+
+// readFile parses a config file into its sections, keyed by profile. The
+// settings before any heading are keyed by "".
+func readFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cur := map[string]string{}
+	sections := map[string]map[string]string{"": cur}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("%s:%d: expected [profile], got %q", path, n, line)
+			}
+			if _, dup := sections[name]; dup {
+				return nil, fmt.Errorf("%s:%d: profile %q repeated", path, n, name)
+			}
+			cur = map[string]string{}
+			sections[name] = cur
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected name = value, got %q", path, n, line)
+		}
+		cur[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return sections, sc.Err()
+}
+
+// Require returns an error naming the first of the settings that is not
+// set, and how to set it.
+func (c *Config) Require(names ...string) error {
+	values := map[string]string{}
+	for _, s := range c.settings() {
+		values[s.name] = *s.value
+	}
+
+	for _, name := range names {
+		v, ok := values[name]
+		if !ok {
+			panic("config: unknown setting " + name)
+		}
+		if v == "" {
+			return fmt.Errorf("%s must be set with -%s, $%s or in the config file", name, name, envName(name))
+		}
+	}
+	return nil
+}
+
+// Client returns a client authenticated with the API key, which must be set.
+func (c *Config) Client(opts ...sfclient.Option) (*sfclient.Client, error) {
+	if err := c.Require(APIKey); err != nil {
+		return nil, err
+	}
+	return sfclient.New(c.APIKey, opts...), nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{APIKey, Account, Venue, Stock, "config", "profile"} {
+		t.Setenv(envName(name), "")
+	}
+	// Point the default location somewhere empty
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+# trading defaults
+apikey = filekey
+account = FILEACCT
+venue = FILEX
+stock=FOO
+`)
+	t.Setenv(envName("config"), path)
+	t.Setenv(envName(Account), "ENVACCT")
+	t.Setenv(envName(Venue), "ENVX")
+
+	var c Config
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.RegisterFlags(flags)
+	if err := flags.Parse([]string{"-venue", "FLAGX"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := Config{APIKey: "filekey", Account: "ENVACCT", Venue: "FLAGX", Stock: "FOO", File: path}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, contents, want string
+	}{
+		{"no equals", "apikey\n", ":1: expected name = value"},
+		{"unknown", "# comment\n\nacount = X\n", `unknown setting "acount"`},
+		{"unknown in profile", "[level]\nstok = X\n", `unknown setting "stok"`},
+		{"bad heading", "[level\n", ":1: expected [profile]"},
+		{"repeated profile", "[level]\n[other]\n[level]\n", `:3: profile "level" repeated`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			c := Config{File: writeConfig(t, test.contents)}
+			err := c.Load()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+apikey = filekey
+venue = TESTEX
+stock = FOOBAR
+
+[first_steps]
+account = EXB123
+venue = FIRSTEX
+
+[chock_a_block]
+account = CAB456
+stock = BLOCK
+`)
+	t.Setenv(envName("config"), path)
+	t.Setenv(envName("profile"), "chock_a_block")
+	t.Setenv(envName(Stock), "ENVSTOCK")
+
+	c := Config{Venue: "FLAGX"}
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Config{APIKey: "filekey", Account: "CAB456", Venue: "FLAGX", Stock: "ENVSTOCK", File: path, Profile: "chock_a_block"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	c = Config{Profile: "first_steps"}
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want = Config{APIKey: "filekey", Account: "EXB123", Venue: "FIRSTEX", Stock: "ENVSTOCK", File: path, Profile: "first_steps"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	c = Config{Profile: "dueling_bulldozers"}
+	if err := c.Load(); err == nil || !strings.Contains(err.Error(), `no section for profile "dueling_bulldozers"`) {
+		t.Errorf("got error %v for a profile not in the file", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	clearEnv(t)
+	var c Config
+	if err := c.Load(); err != nil {
+		t.Errorf("missing default file: got error %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	c = Config{File: missing}
+	if err := c.Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing -config file: got error %v, want not exist", err)
+	}
+
+	t.Setenv(envName("config"), missing)
+	c = Config{}
+	if err := c.Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing $STOCKFIGHTER_CONFIG file: got error %v, want not exist", err)
+	}
+
+	t.Setenv(envName("config"), "")
+	c = Config{Profile: "first_steps"}
+	if err := c.Load(); err == nil {
+		t.Error("expected an error for a profile without a config file")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	c := Config{APIKey: "key", Venue: "TESTEX"}
+	if err := c.Require(APIKey, Venue); err != nil {
+		t.Errorf("Require set settings: %v", err)
+	}
+
+	err := c.Require(APIKey, Stock, Account)
+	if err == nil {
+		t.Fatal("expected an error for an unset stock")
+	}
+	want := "stock must be set with -stock, $STOCKFIGHTER_STOCK or in the config file"
+	if err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var c Config
+	if _, err := c.Client(); err == nil {
+		t.Error("expected an error creating a client without an API key")
+	}
+
+	c.APIKey = "key"
+	client, err := c.Client()
+	if err != nil || client == nil {
+		t.Errorf("Client: got %v, %v", client, err)
+	}
+}
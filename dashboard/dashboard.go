@@ -0,0 +1,336 @@
+// Package dashboard serves a live view of a StockHub over HTTP: the quote,
+// the local order book, our open orders and fills, position and P&L. The
+// page is self contained and updated over server-sent events, so it works
+// without access to anything but the bot.
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+//go:embed index.html
+var page []byte
+
+// Config controls what the dashboard shows and how often it refreshes.
+type Config struct {
+	// Depth is the number of price levels of each side of the book shown.
+	// Defaults to 10.
+	Depth int
+
+	// Fills is the number of most recent fills shown. Defaults to 50.
+	Fills int
+
+	// OrdersInterval is how often open orders are fetched from the
+	// exchange. Fills update orders as they arrive, but orders placed and
+	// cancelled only show up when fetched. 0 disables fetching.
+	OrdersInterval time.Duration
+
+	// BookResync is how often the local book is replaced by a full
+	// snapshot. 0 only seeds it once.
+	BookResync time.Duration
+}
+
+// Order is one of our open orders. Quantity is the amount outstanding.
+type Order struct {
+	ID        int    `json:"id"`
+	Direction string `json:"direction"`
+	Type      string `json:"type"`
+	Price     int    `json:"price"`
+	Quantity  int    `json:"qty"`
+	Filled    int    `json:"filled"`
+}
+
+// Fill is an execution against one of our orders.
+type Fill struct {
+	At        time.Time `json:"at"`
+	OrderID   int       `json:"orderId"`
+	Direction string    `json:"direction"`
+	Price     int       `json:"price"`
+	Quantity  int       `json:"qty"`
+}
+
+// State is everything the dashboard shows. Prices, cash and P&L are in
+// cents.
+type State struct {
+	Account string              `json:"account"`
+	Venue   sfclient.Venue      `json:"venue"`
+	Stock   sfclient.Symbol     `json:"stock"`
+	Quote   sfclient.StockState `json:"quote"`
+	Bids    []sfclient.AskBid   `json:"bids"`
+	Asks    []sfclient.AskBid   `json:"asks"`
+
+	// Orders are open orders, newest first
+	Orders []Order `json:"orders"`
+
+	// Fills are the most recent fills, newest first
+	Fills []Fill `json:"fills"`
+
+	Position int `json:"position"`
+	Cash     int `json:"cash"`
+
+	// PnL is the cash plus the position marked at the last traded price,
+	// or the mid if nothing has traded
+	PnL int `json:"pnl"`
+}
+
+// Dashboard is a StockHub component and an http.Handler. It serves the
+// page at its root, the current State as JSON at state and a stream of
+// States at events, so it can be mounted under any prefix with
+// http.StripPrefix.
+type Dashboard struct {
+	cfg  Config
+	mux  *http.ServeMux
+	book *sfclient.LocalBook
+
+	ticks  chan *sfclient.TickMessage
+	fills  chan *sfclient.FillMessage
+	orders chan []sfclient.OrderState
+
+	mu     *sync.Mutex
+	state  State
+	open   map[int]Order
+	ledger sfclient.Ledger
+	subs   map[chan []byte]struct{}
+	latest []byte
+}
+
+func New(cfg Config) *Dashboard {
+	if cfg.Depth <= 0 {
+		cfg.Depth = 10
+	}
+	if cfg.Fills <= 0 {
+		cfg.Fills = 50
+	}
+
+	d := &Dashboard{
+		cfg:    cfg,
+		mux:    http.NewServeMux(),
+		book:   sfclient.NewLocalBook(0),
+		ticks:  make(chan *sfclient.TickMessage, 100),
+		fills:  make(chan *sfclient.FillMessage, 100),
+		orders: make(chan []sfclient.OrderState, 1),
+		mu:     &sync.Mutex{},
+		open:   map[int]Order{},
+		subs:   map[chan []byte]struct{}{},
+	}
+	d.mux.HandleFunc("/", d.servePage)
+	d.mux.HandleFunc("/state", d.serveState)
+	d.mux.HandleFunc("/events", d.serveEvents)
+	d.publish()
+	return d
+}
+
+func (d *Dashboard) Register(hub *sfclient.StockHub) {
+	d.mu.Lock()
+	d.state.Account, d.state.Venue, d.state.Stock = hub.Account(), hub.Venue(), hub.Stock()
+	d.mu.Unlock()
+
+	d.resync(hub)
+	hub.RegisterToTick(d.ticks)
+	hub.RegisterToFills(d.fills)
+	go d.run(hub)
+	if d.cfg.OrdersInterval > 0 {
+		go d.pollOrders(hub)
+	}
+}
+
+func (d *Dashboard) run(hub *sfclient.StockHub) {
+	var resync <-chan time.Time
+	if d.cfg.BookResync > 0 {
+		t := time.NewTicker(d.cfg.BookResync)
+		defer t.Stop()
+		resync = t.C
+	}
+
+	for {
+		select {
+		case msg := <-d.ticks:
+			if msg.OK {
+				d.tick(msg.Quote)
+			}
+		case msg := <-d.fills:
+			if msg.OK {
+				d.fill(msg)
+			}
+		case orders := <-d.orders:
+			d.setOrders(orders)
+		case <-resync:
+			d.resync(hub)
+		}
+		d.publish()
+	}
+}
+
+func (d *Dashboard) resync(hub *sfclient.StockHub) {
+	book, err := hub.OrderBook()
+	if err != nil {
+		hub.Logger().Warn("dashboard: error fetching order book", "err", err)
+		return
+	}
+	d.book.Seed(book)
+}
+
+func (d *Dashboard) pollOrders(hub *sfclient.StockHub) {
+	t := time.NewTicker(d.cfg.OrdersInterval)
+	defer t.Stop()
+
+	for range t.C {
+		resp, err := hub.Orders()
+		if err != nil {
+			hub.Logger().Warn("dashboard: error fetching orders", "err", err)
+			continue
+		}
+		d.orders <- resp.Orders
+	}
+}
+
+func (d *Dashboard) tick(q sfclient.StockState) {
+	d.book.Apply(q)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.Quote = q
+	d.ledger.Quote(q)
+}
+
+func (d *Dashboard) fill(msg *sfclient.FillMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	o := msg.Order
+	d.ledger.Fill(o.Direction == "buy", msg.Price, msg.Filled)
+
+	at := msg.FilledAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	fill := Fill{At: at, OrderID: o.ID, Direction: o.Direction, Price: msg.Price, Quantity: msg.Filled}
+	d.state.Fills = append([]Fill{fill}, d.state.Fills[:min(len(d.state.Fills), d.cfg.Fills-1)]...)
+
+	if o.Open {
+		d.open[o.ID] = Order{ID: o.ID, Direction: o.Direction, Type: o.OrderType, Price: o.Price, Quantity: o.Quantity, Filled: o.TotalFilled}
+	} else {
+		delete(d.open, o.ID)
+	}
+}
+
+func (d *Dashboard) setOrders(orders []sfclient.OrderState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.open = map[int]Order{}
+	for _, o := range orders {
+		if o.Open {
+			d.open[o.ID] = Order{ID: o.ID, Direction: o.Direction, Type: string(o.OrderType), Price: o.Price, Quantity: o.Quantity, Filled: o.TotalFilled}
+		}
+	}
+}
+
+// State returns what the dashboard is currently showing.
+func (d *Dashboard) State() State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.snapshot()
+}
+
+// snapshot returns a copy of the state. Must be called with d.mu held.
+func (d *Dashboard) snapshot() State {
+	s := d.state
+	s.Bids, s.Asks = d.book.Depth(d.cfg.Depth)
+	s.Position, s.Cash, s.PnL = d.ledger.Position, d.ledger.Cash, d.ledger.PnL()
+	s.Fills = append([]Fill(nil), s.Fills...)
+
+	s.Orders = make([]Order, 0, len(d.open))
+	for _, o := range d.open {
+		s.Orders = append(s.Orders, o)
+	}
+	sort.Slice(s.Orders, func(i, j int) bool { return s.Orders[i].ID > s.Orders[j].ID })
+	return s
+}
+
+// publish sends the current state to every subscriber. A subscriber that
+// has not yet received the previous state only gets the latest.
+func (d *Dashboard) publish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := json.Marshal(d.snapshot())
+	if err != nil {
+		return
+	}
+	d.latest = data
+	for ch := range d.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- data
+	}
+}
+
+func (d *Dashboard) subscribe() chan []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ch := make(chan []byte, 1)
+	ch <- d.latest
+	d.subs[ch] = struct{}{}
+	return ch
+}
+
+func (d *Dashboard) unsubscribe(ch chan []byte) {
+	d.mu.Lock()
+	delete(d.subs, ch)
+	d.mu.Unlock()
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+func (d *Dashboard) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+func (d *Dashboard) serveState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.State())
+}
+
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch := d.subscribe()
+	defer d.unsubscribe(ch)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
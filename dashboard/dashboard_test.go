@@ -0,0 +1,133 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+func TestDashboard(t *testing.T) {
+	m := sftest.NewMarket()
+	m.SetBook(&sfclient.StockOrderBookResponse{
+		Bids: []sfclient.AskBid{{Price: 99, Quantity: 5, IsBuy: true}, {Price: 98, Quantity: 7, IsBuy: true}},
+		Asks: []sfclient.AskBid{{Price: 101, Quantity: 3}},
+	})
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	d := New(Config{Depth: 1})
+	hub.RegisterComponenets(d)
+
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	events := make(chan State)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			data, ok := strings.CutPrefix(sc.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var s State
+			if err := json.Unmarshal([]byte(data), &s); err != nil {
+				t.Errorf("error decoding event %q: %v", data, err)
+			}
+			select {
+			case events <- s:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	order := sfclient.OrderResponse{ID: 3, Direction: "buy", OrderType: "limit", Price: 100, Quantity: 6, TotalFilled: 4, Open: true}
+	m.PushFill(&sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Order: order, Price: 100, Filled: 4})
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid, tick.Quote.BidSize, tick.Quote.Ask, tick.Quote.AskSize, tick.Quote.Last = 100, 6, 101, 3, 102
+	tick.Quote.BidDepth, tick.Quote.AskDepth = 18, 3
+	m.PushTick(tick)
+
+	// Events may be coalesced, so wait for one reflecting both messages
+	timeout := time.After(time.Second)
+	var s State
+	for s.Quote.Last == 0 || len(s.Fills) == 0 {
+		select {
+		case s = <-events:
+		case <-timeout:
+			t.Fatalf("expected state with tick and fill, got %+v", s)
+		}
+	}
+
+	if s.Stock != "FOOBAR" || s.Venue != "TESTEX" || s.Account != "EXB123456" {
+		t.Errorf("unexpected hub details: %+v", s)
+	}
+	if len(s.Bids) != 1 || s.Bids[0].Price != 100 || len(s.Asks) != 1 || s.Asks[0].Price != 101 {
+		t.Errorf("unexpected book: bids %v asks %v", s.Bids, s.Asks)
+	}
+	if len(s.Orders) != 1 || s.Orders[0].ID != 3 || s.Orders[0].Quantity != 6 {
+		t.Errorf("unexpected orders: %+v", s.Orders)
+	}
+	if s.Position != 4 || s.Cash != -400 || s.PnL != 8 {
+		t.Errorf("unexpected position %d, cash %d, pnl %d", s.Position, s.Cash, s.PnL)
+	}
+
+	// The order completing removes it
+	order.Quantity, order.TotalFilled, order.Open = 0, 10, false
+	m.PushFill(&sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Order: order, Price: 100, Filled: 6})
+	for len(s.Fills) != 2 {
+		select {
+		case s = <-events:
+		case <-timeout:
+			t.Fatalf("expected second fill, got %+v", s)
+		}
+	}
+	if len(s.Orders) != 0 || s.Fills[0].Quantity != 6 || s.Position != 10 {
+		t.Errorf("unexpected state after order completed: %+v", s)
+	}
+}
+
+func TestDashboardPage(t *testing.T) {
+	srv := httptest.NewServer(http.StripPrefix("/dash", New(Config{})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/dash/")
+	if err != nil {
+		t.Fatalf("error fetching page: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("unexpected page response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Get(srv.URL + "/dash/state")
+	if err != nil {
+		t.Fatalf("error fetching state: %v", err)
+	}
+	defer resp.Body.Close()
+	var s State
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Errorf("error decoding state: %v", err)
+	}
+}
@@ -3,30 +3,95 @@ package main
 import (
 	"flag"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/ifross89/stockfighter/config"
+	"github.com/ifross89/stockfighter/dashboard"
+	"github.com/ifross89/stockfighter/metrics"
+	"github.com/ifross89/stockfighter/paper"
 	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/strats"
 )
 
-var apiKey string
-var account = "HAT11711279"
-var stock = sfclient.Symbol("EPC")
-var venue = sfclient.Venue("FCNEX")
+var cfg config.Config
+var maxExposure int
+var requote time.Duration
+var paperTrade bool
+var metricsAddr string
+var dashboardAddr string
+var logLevel slog.Level
 
 func init() {
-	flag.StringVar(&apiKey, "apikey", "", "api key to use for authentication")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.IntVar(&maxExposure, "maxexposure", 1000, "largest position, long or short, to hold")
+	flag.DurationVar(&requote, "requote", 5*time.Second, "how often to refresh quotes when nothing trades")
+	flag.BoolVar(&paperTrade, "paper", false, "simulate fills against live quotes instead of sending orders")
+	flag.TextVar(&logLevel, "loglevel", slog.LevelInfo, "least severe level to log: debug, info, warn or error")
+	flag.StringVar(&dashboardAddr, "dashboard", "", "address, such as localhost:8080, to serve a live dashboard on")
+	flag.StringVar(&metricsAddr, "metrics", "", "address, such as localhost:9100, to serve Prometheus metrics on")
 }
 
 func main() {
 	flag.Parse()
 
-	if apiKey == "" {
-		log.Fatal("please provide an API KEY with --apikey")
+	if err := cfg.Load(); err != nil {
+		log.Fatalf("error loading config: %v", err)
 	}
+	if err := cfg.Require(config.APIKey, config.Account, config.Venue, config.Stock); err != nil {
+		log.Fatalf("could not start: %v", err)
+	}
+	account, venue, stock := cfg.Account, sfclient.Venue(cfg.Venue), sfclient.Symbol(cfg.Stock)
 
-	mm, err := New(apiKey, account, venue, stock, 1000)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+	c, err := cfg.Client(sfclient.WithLogger(logger))
 	if err != nil {
-		panic(err)
+		log.Fatalf("error creating client: %v", err)
+	}
+
+	var og sfclient.OrderGateway = c
+	var pg *paper.Gateway
+	if paperTrade {
+		pg, err = paper.New(c, account, venue, stock, paper.Config{BookInterval: time.Second})
+		if err != nil {
+			log.Fatalf("error creating paper gateway: %v", err)
+		}
+		defer pg.Close()
+		og = pg
+	}
+
+	hub, err := sfclient.NewStockHub(c, og, account, venue, stock)
+	if err != nil {
+		log.Fatalf("error creating hub: %v", err)
+	}
+
+	if metricsAddr != "" {
+		pos := metrics.NewPosition()
+		hub.RegisterComponenets(pos)
+
+		reg := metrics.NewRegistry()
+		reg.Register(metrics.NewClientCollector(c.Instruments()), metrics.NewHubCollector(hub), pos)
+		go func() {
+			log.Printf("metrics server stopped: %v", metrics.ListenAndServe(metricsAddr, reg))
+		}()
+	}
+
+	if dashboardAddr != "" {
+		dash := dashboard.New(dashboard.Config{OrdersInterval: 2 * time.Second, BookResync: 10 * time.Second})
+		hub.RegisterComponenets(dash)
+		go func() {
+			log.Printf("dashboard server stopped: %v", http.ListenAndServe(dashboardAddr, dash))
+		}()
 	}
 
-	mm.exec()
+	mm := strats.NewMarketMaker(maxExposure)
+	if err := strats.NewRunner(hub, mm, requote).Run(); err != nil {
+		log.Fatalf("market maker stopped: %v", err)
+	}
+
+	if pg != nil {
+		log.Printf("paper portfolio: %+v", pg.Portfolio())
+	}
 }
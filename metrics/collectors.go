@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+const namespace = "stockfighter_"
+
+// ClientCollector reports the requests made by a client, their errors and
+// latencies, websocket lag and reconnects, and the time from sending orders
+// to their first fill, as recorded by its sfclient.Instruments.
+type ClientCollector struct {
+	in *sfclient.Instruments
+}
+
+func NewClientCollector(in *sfclient.Instruments) *ClientCollector {
+	return &ClientCollector{in: in}
+}
+
+func (c *ClientCollector) Collect() []Family {
+	requests := Family{Name: namespace + "requests_total", Help: "API requests made, by endpoint.", Type: Counter}
+	errors := Family{Name: namespace + "request_errors_total", Help: "API requests that failed, by endpoint and class of error.", Type: Counter}
+	durations := Family{Name: namespace + "request_duration_seconds", Help: "Latency of API requests, by endpoint.", Type: Summary}
+	for _, name := range c.in.Endpoints() {
+		s := c.in.Endpoint(name)
+		endpoint := Label{"endpoint", name}
+		requests.Samples = append(requests.Samples, Sample{Labels: []Label{endpoint}, Value: float64(s.Count)})
+
+		classes := make([]string, 0, len(s.ErrorClasses))
+		for class := range s.ErrorClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			errors.Samples = append(errors.Samples, Sample{
+				Labels: []Label{endpoint, {"class", class}},
+				Value:  float64(s.ErrorClasses[class]),
+			})
+		}
+
+		durations.Samples = append(durations.Samples, summarySamples(s, endpoint)...)
+	}
+
+	lag := Family{Name: namespace + "websocket_lag_seconds", Help: "Delay between the exchange timestamping a message and it arriving, by stream.", Type: Summary}
+	lag.Samples = append(summarySamples(c.in.TickLag(), Label{"stream", sfclient.StreamTicks}),
+		summarySamples(c.in.FillLag(), Label{"stream", sfclient.StreamFills})...)
+
+	orderToFill := Family{Name: namespace + "order_to_fill_seconds", Help: "Time from sending an order to its first fill.", Type: Summary}
+	orderToFill.Samples = summarySamples(c.in.OrderToFill())
+
+	reconnects := Family{Name: namespace + "websocket_reconnects_total", Help: "Attempts to reconnect dropped websockets, by stream and result.", Type: Counter}
+	byStream := c.in.Reconnects()
+	streams := make([]string, 0, len(byStream))
+	for stream := range byStream {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	for _, stream := range streams {
+		r := byStream[stream]
+		reconnects.Samples = append(reconnects.Samples,
+			Sample{Labels: []Label{{"stream", stream}, {"result", "ok"}}, Value: float64(r.OK)},
+			Sample{Labels: []Label{{"stream", stream}, {"result", "failed"}}, Value: float64(r.Failed)})
+	}
+
+	return []Family{requests, errors, durations, lag, orderToFill, reconnects}
+}
+
+// summarySamples returns the quantiles, sum and count of s in seconds. The
+// quantiles of an empty summary are NaN, as there is nothing to estimate.
+func summarySamples(s sfclient.LatencySummary, labels ...Label) []Sample {
+	with := func(extra ...Label) []Label {
+		return append(append([]Label(nil), labels...), extra...)
+	}
+
+	var samples []Sample
+	for _, q := range []struct {
+		quantile float64
+		value    time.Duration
+	}{{0.5, s.P50}, {0.9, s.P90}, {0.99, s.P99}} {
+		v := q.value.Seconds()
+		if s.Count == 0 {
+			v = math.NaN()
+		}
+		samples = append(samples, Sample{
+			Labels: with(Label{"quantile", strconv.FormatFloat(q.quantile, 'g', -1, 64)}),
+			Value:  v,
+		})
+	}
+	return append(samples,
+		Sample{Suffix: "_sum", Labels: with(), Value: s.Sum.Seconds()},
+		Sample{Suffix: "_count", Labels: with(), Value: float64(s.Count)})
+}
+
+// HubCollector reports the ticks and fills a hub could not deliver to its
+// listeners because they were not keeping up.
+type HubCollector struct {
+	hub *sfclient.StockHub
+}
+
+func NewHubCollector(hub *sfclient.StockHub) *HubCollector {
+	return &HubCollector{hub: hub}
+}
+
+func (c *HubCollector) Collect() []Family {
+	ticks, fills := c.hub.Dropped()
+	labels := func(stream string) []Label {
+		return []Label{{"venue", string(c.hub.Venue())}, {"stock", string(c.hub.Stock())}, {"stream", stream}}
+	}
+	return []Family{{
+		Name: namespace + "hub_dropped_total",
+		Help: "Messages not delivered to a hub listener because it was full, by stream.",
+		Type: Counter,
+		Samples: []Sample{
+			{Labels: labels(sfclient.StreamTicks), Value: float64(ticks)},
+			{Labels: labels(sfclient.StreamFills), Value: float64(fills)},
+		},
+	}}
+}
+
+// Position reports the position, cash and P&L of the account in the stock
+// of the hub it is registered with, as kept by an sfclient.Position.
+type Position struct {
+	*sfclient.Position
+	venue sfclient.Venue
+	stock sfclient.Symbol
+}
+
+func NewPosition() *Position {
+	return &Position{Position: sfclient.NewPosition()}
+}
+
+func (p *Position) Register(hub *sfclient.StockHub) {
+	p.venue, p.stock = hub.Venue(), hub.Stock()
+	p.Position.Register(hub)
+}
+
+// Values returns the position, cash and P&L.
+func (p *Position) Values() (position, cash, pnl int) {
+	l := p.Ledger()
+	return l.Position, l.Cash, l.PnL()
+}
+
+func (p *Position) Collect() []Family {
+	position, cash, pnl := p.Values()
+	labels := []Label{{"venue", string(p.venue)}, {"stock", string(p.stock)}}
+	gauge := func(name, help string, v int) Family {
+		return Family{Name: namespace + name, Help: help, Type: Gauge, Samples: []Sample{{Labels: labels, Value: float64(v)}}}
+	}
+	return []Family{
+		gauge("position_shares", "Shares held, negative if short.", position),
+		gauge("cash_cents", "Cash from trading.", cash),
+		gauge("pnl_cents", "Cash plus the position marked at the last price.", pnl),
+	}
+}
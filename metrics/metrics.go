@@ -0,0 +1,178 @@
+// Package metrics exposes the health of a running bot in the Prometheus text
+// exposition format: request and error counts, websocket reconnects,
+// dropped hub messages, position, P&L and latency quantiles. Nothing is
+// collected unless a Registry is created, and values are read from their
+// sources only when scraped.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the type of a metric family.
+type Type string
+
+const (
+	Counter Type = "counter"
+	Gauge   Type = "gauge"
+	Summary Type = "summary"
+)
+
+// Label is a name and value distinguishing samples of a family.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a family. Suffix is appended to the family's
+// name, as with the _sum and _count of a summary.
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family is a group of samples of the same metric.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// Collector reports the current value of some metrics. It is called on
+// every scrape, possibly concurrently with the bot updating them.
+type Collector interface {
+	Collect() []Family
+}
+
+// GaugeFunc is a Collector reporting a single gauge whose value is returned
+// by a function, for exposing values a bot already keeps.
+type GaugeFunc struct {
+	Name string
+	Help string
+	Func func() float64
+}
+
+func (g GaugeFunc) Collect() []Family {
+	return []Family{{Name: g.Name, Help: g.Help, Type: Gauge, Samples: []Sample{{Value: g.Func()}}}}
+}
+
+// Registry gathers the metrics of several collectors. It serves them over
+// HTTP as an http.Handler.
+type Registry struct {
+	mu         *sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{mu: &sync.Mutex{}}
+}
+
+func (r *Registry) Register(cs ...Collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, cs...)
+	r.mu.Unlock()
+}
+
+// Gather collects every registered metric, sorted by name. Families of the
+// same name from different collectors, such as the positions of two
+// stocks, are combined.
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	cs := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	byName := map[string]*Family{}
+	var names []string
+	for _, c := range cs {
+		for _, f := range c.Collect() {
+			if prev, ok := byName[f.Name]; ok {
+				prev.Samples = append(prev.Samples, f.Samples...)
+				continue
+			}
+			f := f
+			byName[f.Name] = &f
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+
+	fams := make([]Family, len(names))
+	for i, name := range names {
+		fams[i] = *byName[name]
+	}
+	return fams
+}
+
+// WriteText writes every registered metric to w in the Prometheus text
+// format.
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		writeFamily(bw, f)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// ListenAndServe serves the metrics of r at /metrics on addr, such as
+// "localhost:9100". It blocks until the server fails.
+func ListenAndServe(addr string, r *Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	return http.ListenAndServe(addr, mux)
+}
+
+func writeFamily(w *bufio.Writer, f Family) {
+	if f.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.Name, helpEscaper.Replace(f.Help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.Name, f.Type)
+	for _, s := range f.Samples {
+		w.WriteString(f.Name)
+		w.WriteString(s.Suffix)
+		if len(s.Labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range s.Labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l.Name, labelEscaper.Replace(l.Value))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatValue(s.Value))
+		w.WriteByte('\n')
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
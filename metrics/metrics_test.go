@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+type families []Family
+
+func (f families) Collect() []Family {
+	return f
+}
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Register(
+		families{{Name: "b_total", Help: "Second\\line\nhere.", Type: Counter, Samples: []Sample{
+			{Labels: []Label{{"path", `a"b\c`}}, Value: 3},
+		}}},
+		GaugeFunc{Name: "a", Help: "First.", Func: func() float64 { return 0.25 }},
+		families{{Name: "b_total", Type: Counter, Samples: []Sample{
+			{Labels: []Label{{"path", "d"}}, Value: math.Inf(1)},
+		}}},
+	)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("error scraping: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	want := `# HELP a First.
+# TYPE a gauge
+a 0.25
+# HELP b_total Second\\line\nhere.
+# TYPE b_total counter
+b_total{path="a\"b\\c"} 3
+b_total{path="d"} +Inf
+`
+	if string(body) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func scrape(t *testing.T, r *Registry) string {
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("error writing metrics: %v", err)
+	}
+	return sb.String()
+}
+
+func expectLines(t *testing.T, text string, lines ...string) {
+	t.Helper()
+	for _, l := range lines {
+		if !strings.Contains(text, l+"\n") {
+			t.Errorf("expected %q in\n%s", l, text)
+		}
+	}
+}
+
+func TestClientCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/heartbeat") && strings.Contains(r.URL.Path, "venues") {
+			fmt.Fprint(w, `{"ok": false, "error": "no such venue"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	defer srv.Close()
+
+	c := sfclient.New("key", sfclient.WithBaseURL(srv.URL+"/", "ws://unused/"))
+	c.Heartbeat()
+	c.Heartbeat()
+	c.VenueHeartbeat("TESTEX")
+
+	r := NewRegistry()
+	r.Register(NewClientCollector(c.Instruments()))
+	text := scrape(t, r)
+
+	expectLines(t, text,
+		"# TYPE stockfighter_requests_total counter",
+		`stockfighter_requests_total{endpoint="heartbeat"} 2`,
+		`stockfighter_requests_total{endpoint="venue_heartbeat"} 1`,
+		`stockfighter_request_errors_total{endpoint="venue_heartbeat",class="api"} 1`,
+		"# TYPE stockfighter_request_duration_seconds summary",
+		`stockfighter_request_duration_seconds_count{endpoint="heartbeat"} 2`,
+		`stockfighter_order_to_fill_seconds_count 0`,
+		`stockfighter_websocket_lag_seconds{stream="ticks",quantile="0.99"} NaN`,
+	)
+	if strings.Contains(text, `stockfighter_request_errors_total{endpoint="heartbeat"`) {
+		t.Errorf("expected no heartbeat errors in\n%s", text)
+	}
+}
+
+func TestHubMetrics(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	pos := NewPosition()
+	hub.RegisterComponenets(pos)
+	hub.RegisterToTick(make(chan *sfclient.TickMessage))
+
+	fill := func(dir string, price, qty int) {
+		msg := &sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Price: price, Filled: qty}
+		msg.Order.Direction = dir
+		m.PushFill(msg)
+	}
+	fill("buy", 100, 10)
+	fill("sell", 110, 4)
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Last = 105
+	m.PushTick(tick)
+
+	// position 6, cash -1000+440, marked at 105
+	deadline := time.Now().Add(time.Second)
+	for {
+		position, cash, pnl := pos.Values()
+		dropped, _ := hub.Dropped()
+		if position == 6 && cash == -560 && pnl == 70 && dropped == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected position %d, cash %d, pnl %d", position, cash, pnl)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r := NewRegistry()
+	r.Register(pos, NewHubCollector(hub))
+	expectLines(t, scrape(t, r),
+		"# TYPE stockfighter_position_shares gauge",
+		`stockfighter_position_shares{venue="TESTEX",stock="FOOBAR"} 6`,
+		`stockfighter_cash_cents{venue="TESTEX",stock="FOOBAR"} -560`,
+		`stockfighter_pnl_cents{venue="TESTEX",stock="FOOBAR"} 70`,
+		`stockfighter_hub_dropped_total{venue="TESTEX",stock="FOOBAR",stream="ticks"} 1`,
+		`stockfighter_hub_dropped_total{venue="TESTEX",stock="FOOBAR",stream="fills"} 0`,
+	)
+}
@@ -0,0 +1,150 @@
+package paper
+
+import (
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// All matching functions must be called with g.mu held. They return the
+// executions to report once it is released.
+
+func crosses(o *sfclient.OrderResponse, price int) bool {
+	if o.Direction == "buy" {
+		return price <= o.Price
+	}
+	return price >= o.Price
+}
+
+// matchIncoming executes a new order against the opposite side of the book
+// snapshot. Executed quantity is removed from the snapshot so it cannot be
+// taken twice before the next refresh.
+func (g *Gateway) matchIncoming(o *sfclient.OrderResponse, typ sfclient.OrderType) []*sfclient.FillMessage {
+	levels := g.book.Asks
+	if o.Direction == "sell" {
+		levels = g.book.Bids
+	}
+
+	if typ == sfclient.TypeFillOrKill {
+		var avail int
+		for _, l := range levels {
+			if crosses(o, l.Price) {
+				avail += l.Quantity
+			}
+		}
+		if avail < o.Quantity {
+			return nil
+		}
+	}
+
+	var fills []*sfclient.FillMessage
+	for i := range levels {
+		l := &levels[i]
+		if o.Quantity == 0 {
+			break
+		}
+		if l.Quantity == 0 {
+			continue
+		}
+		if typ != sfclient.TypeMarket && !crosses(o, l.Price) {
+			break
+		}
+
+		qty := min(l.Quantity, o.Quantity)
+		l.Quantity -= qty
+		fills = append(fills, g.fill(o, l.Price, qty, false))
+	}
+	return fills
+}
+
+// matchTick executes resting orders against the size the latest quote
+// shows at or through their price, then against the latest trade. Size
+// taken by an earlier order, or on an earlier tick, is not taken again.
+func (g *Gateway) matchTick() []*sfclient.FillMessage {
+	var fills []*sfclient.FillMessage
+	for _, o := range g.resting() {
+		isBuy := o.Direction == "buy"
+		if price, avail := g.touch.Offered(isBuy); price > 0 && crosses(o, price) && avail > 0 {
+			qty := min(avail, o.Quantity)
+			g.touch.Take(isBuy, qty)
+			fills = append(fills, g.fill(o, o.Price, qty, true))
+		}
+
+		_, price, left := g.touch.Traded()
+		if !o.Open || left == 0 || !crosses(o, price) {
+			continue
+		}
+		if price != o.Price || g.cfg.FillAtTouch {
+			qty := min(left, o.Quantity)
+			g.touch.Allot(qty)
+			fills = append(fills, g.fill(o, o.Price, qty, true))
+		}
+	}
+	return fills
+}
+
+// matchBook executes resting orders crossed by a fresh book snapshot.
+func (g *Gateway) matchBook() []*sfclient.FillMessage {
+	var fills []*sfclient.FillMessage
+	for _, o := range g.resting() {
+		levels := g.book.Asks
+		if o.Direction == "sell" {
+			levels = g.book.Bids
+		}
+
+		for i := range levels {
+			l := &levels[i]
+			if o.Quantity == 0 || !crosses(o, l.Price) {
+				break
+			}
+
+			qty := min(l.Quantity, o.Quantity)
+			l.Quantity -= qty
+			if qty > 0 {
+				fills = append(fills, g.fill(o, o.Price, qty, true))
+			}
+		}
+	}
+	return fills
+}
+
+// resting returns the open orders in the order they were placed.
+func (g *Gateway) resting() []*sfclient.OrderResponse {
+	var open []*sfclient.OrderResponse
+	for id := 1; id <= g.nextID; id++ {
+		if o := g.orders[id]; o.Open {
+			open = append(open, o)
+		}
+	}
+	return open
+}
+
+// fill executes qty of o at price and updates the portfolio.
+func (g *Gateway) fill(o *sfclient.OrderResponse, price, qty int, standing bool) *sfclient.FillMessage {
+	isBuy := o.Direction == "buy"
+	o.Quantity -= qty
+	o.TotalFilled += qty
+	o.Fills = append(o.Fills, sfclient.AskBid{Price: price, Quantity: qty, IsBuy: isBuy})
+	if o.Quantity == 0 {
+		o.Open = false
+	}
+
+	g.portfolio.Fill(isBuy, price, qty)
+
+	msg := &sfclient.FillMessage{
+		APIResponse: sfclient.APIResponse{OK: true},
+		Account:     g.account,
+		Venue:       g.venue,
+		Symbol:      g.stock,
+		Order:       *copyOrder(o),
+		Price:       price,
+		Filled:      qty,
+		FilledAt:    time.Now(),
+	}
+	if standing {
+		msg.StandingID, msg.StandingComplete = o.ID, !o.Open
+	} else {
+		msg.IncomingID, msg.IncomingComplete = o.ID, !o.Open
+	}
+	return msg
+}
@@ -0,0 +1,341 @@
+// Package paper provides an sfclient.OrderGateway that simulates executions
+// against live market data instead of sending orders to the exchange.
+package paper
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Config controls how the paper gateway simulates the exchange.
+type Config struct {
+	// BookInterval is how often the order book snapshot used to match
+	// incoming orders is refreshed. 0 disables polling, so only the
+	// snapshot taken on creation is used.
+	BookInterval time.Duration
+
+	// FillAtTouch lets trades printed at exactly our price fill resting
+	// orders. When false only trades through our price fill them, which
+	// assumes we are always at the back of the queue.
+	FillAtTouch bool
+}
+
+// Gateway is a paper trading sfclient.OrderGateway for a single stock.
+// Incoming orders execute against the latest order book snapshot; resting
+// orders execute when the tickertape shows the market trading or quoting
+// through them.
+type Gateway struct {
+	md      sfclient.MarketData
+	cfg     Config
+	account string
+	venue   sfclient.Venue
+	stock   sfclient.Symbol
+	ticks   sfclient.TickSource
+	done    chan struct{}
+	logger  *slog.Logger
+
+	closeOnce *sync.Once
+
+	mu        *sync.Mutex
+	book      *sfclient.StockOrderBookResponse
+	touch     sfclient.Touch
+	orders    map[int]*sfclient.OrderResponse
+	nextID    int
+	portfolio sfclient.Ledger
+	sources   []*fillSource
+}
+
+// New creates a gateway trading stock on venue for account, using md for
+// quotes and order books.
+func New(md sfclient.MarketData, account string, venue sfclient.Venue, stock sfclient.Symbol, cfg Config) (*Gateway, error) {
+	book, err := md.StockOrderBook(venue, stock)
+	if err != nil {
+		return nil, err
+	}
+
+	ticks, err := md.StockTickSource(account, venue, stock)
+	if err != nil {
+		return nil, err
+	}
+
+	tickch, err := ticks.Listen()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := sfclient.LoggerOf(md).With(
+		sfclient.LogAccount, account, sfclient.LogVenue, string(venue), sfclient.LogSymbol, string(stock))
+	g := &Gateway{
+		md:      md,
+		cfg:     cfg,
+		account: account,
+		venue:   venue,
+		stock:   stock,
+		ticks:   ticks,
+		done:    make(chan struct{}),
+		mu:      &sync.Mutex{},
+		book:    ownBook(book),
+		logger:  logger,
+		orders:  make(map[int]*sfclient.OrderResponse),
+
+		closeOnce: &sync.Once{},
+	}
+
+	go g.listen(tickch)
+	if cfg.BookInterval > 0 {
+		go g.pollBook()
+	}
+
+	return g, nil
+}
+
+// Close stops following the market. Resting orders no longer execute.
+// Calling it again has no effect.
+func (g *Gateway) Close() {
+	g.closeOnce.Do(func() {
+		close(g.done)
+		g.ticks.Close()
+	})
+}
+
+// Portfolio returns the current paper position, marked from the
+// tickertape.
+func (g *Gateway) Portfolio() sfclient.Ledger {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.portfolio
+}
+
+func (g *Gateway) listen(tickch <-chan *sfclient.TickMessage) {
+	for msg := range tickch {
+		if !msg.OK {
+			continue
+		}
+
+		g.mu.Lock()
+		g.touch.Update(msg.Quote)
+		g.portfolio.Quote(msg.Quote)
+		fills := g.matchTick()
+		g.mu.Unlock()
+
+		g.send(fills)
+	}
+}
+
+func (g *Gateway) pollBook() {
+	t := time.NewTicker(g.cfg.BookInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-t.C:
+			book, err := g.md.StockOrderBook(g.venue, g.stock)
+			if err != nil {
+				g.logger.Warn("paper: error refreshing order book", "err", err)
+				continue
+			}
+
+			g.mu.Lock()
+			g.book = ownBook(book)
+			fills := g.matchBook()
+			g.mu.Unlock()
+
+			g.send(fills)
+		}
+	}
+}
+
+// ownBook copies the levels of book, which matching consumes, so that the
+// snapshot returned by the market data is left as it was.
+func ownBook(book *sfclient.StockOrderBookResponse) *sfclient.StockOrderBookResponse {
+	cp := *book
+	cp.Bids = slices.Clone(book.Bids)
+	cp.Asks = slices.Clone(book.Asks)
+	return &cp
+}
+
+func (g *Gateway) check(account string, venue sfclient.Venue, stock sfclient.Symbol) error {
+	if account != g.account || venue != g.venue || stock != g.stock {
+		return errors.New("paper gateway only trades " + g.stock.String() + " on " + g.venue.String() + " for " + g.account)
+	}
+	return nil
+}
+
+func (g *Gateway) place(account string, venue sfclient.Venue, stock sfclient.Symbol, price, qty int, typ sfclient.OrderType, direction string) (*sfclient.OrderResponse, error) {
+	if err := g.check(account, venue, stock); err != nil {
+		return nil, err
+	}
+	if qty <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	g.mu.Lock()
+	g.nextID++
+	o := &sfclient.OrderResponse{
+		APIResponse:      sfclient.APIResponse{OK: true},
+		Symbol:           stock,
+		Venue:            venue,
+		Direction:        direction,
+		OriginalQuantity: qty,
+		Quantity:         qty,
+		Price:            price,
+		OrderType:        string(typ),
+		ID:               g.nextID,
+		Account:          account,
+		Timestamp:        time.Now(),
+		Open:             true,
+	}
+	g.orders[o.ID] = o
+
+	fills := g.matchIncoming(o, typ)
+	if typ != sfclient.TypeLimit {
+		// Anything not executed immediately is cancelled
+		o.Open, o.Quantity = false, 0
+	}
+	resp := copyOrder(o)
+	g.mu.Unlock()
+
+	g.send(fills)
+	return resp, nil
+}
+
+func (g *Gateway) BuyOrder(account string, venue sfclient.Venue, stock sfclient.Symbol, price int, quantity int, orderType sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return g.place(account, venue, stock, price, quantity, orderType, "buy")
+}
+
+func (g *Gateway) SellOrder(account string, venue sfclient.Venue, stock sfclient.Symbol, price int, quantity int, orderType sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return g.place(account, venue, stock, price, quantity, orderType, "sell")
+}
+
+func (g *Gateway) CancelOrder(venue sfclient.Venue, stock sfclient.Symbol, id int) (*sfclient.CancelOrderResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o, ok := g.orders[id]
+	if !ok || venue != g.venue || stock != g.stock {
+		return nil, errors.New("no such order")
+	}
+	o.Open, o.Quantity = false, 0
+	return &sfclient.CancelOrderResponse{APIResponse: sfclient.APIResponse{OK: true}, OrderState: toState(o)}, nil
+}
+
+func (g *Gateway) OrderStatus(venue sfclient.Venue, stock sfclient.Symbol, id int) (*sfclient.StatusResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o, ok := g.orders[id]
+	if !ok || venue != g.venue || stock != g.stock {
+		return nil, errors.New("no such order")
+	}
+	return &sfclient.StatusResponse{APIResponse: sfclient.APIResponse{OK: true}, OrderState: toState(o)}, nil
+}
+
+func (g *Gateway) StockOrdersStatus(account string, venue sfclient.Venue, stock sfclient.Symbol) (*sfclient.MultiStatusResponse, error) {
+	if err := g.check(account, venue, stock); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	resp := &sfclient.MultiStatusResponse{APIResponse: sfclient.APIResponse{OK: true}}
+	for id := 1; id <= g.nextID; id++ {
+		resp.Orders = append(resp.Orders, toState(g.orders[id]))
+	}
+	return resp, nil
+}
+
+func (g *Gateway) StockFillSource(account string, venue sfclient.Venue, stock sfclient.Symbol) (sfclient.FillSource, error) {
+	if err := g.check(account, venue, stock); err != nil {
+		return nil, err
+	}
+
+	s := &fillSource{mu: &sync.Mutex{}, messages: make(chan *sfclient.FillMessage, 100), logger: g.logger}
+	g.mu.Lock()
+	g.sources = append(g.sources, s)
+	g.mu.Unlock()
+	return s, nil
+}
+
+func (g *Gateway) send(fills []*sfclient.FillMessage) {
+	if len(fills) == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	sources := append([]*fillSource(nil), g.sources...)
+	g.mu.Unlock()
+
+	for _, msg := range fills {
+		for _, s := range sources {
+			s.send(msg)
+		}
+	}
+}
+
+func copyOrder(o *sfclient.OrderResponse) *sfclient.OrderResponse {
+	cp := *o
+	cp.Fills = append([]sfclient.AskBid(nil), o.Fills...)
+	return &cp
+}
+
+func toState(o *sfclient.OrderResponse) sfclient.OrderState {
+	return sfclient.OrderState{
+		Symbol:           o.Symbol,
+		Venue:            o.Venue,
+		Direction:        o.Direction,
+		OriginalQuantity: o.OriginalQuantity,
+		Quantity:         o.Quantity,
+		Price:            o.Price,
+		OrderType:        sfclient.OrderType(o.OrderType),
+		ID:               o.ID,
+		Account:          o.Account,
+		Timestamp:        o.Timestamp,
+		Fills:            append([]sfclient.AskBid(nil), o.Fills...),
+		TotalFilled:      o.TotalFilled,
+		Open:             o.Open,
+	}
+}
+
+// fillSource is a sfclient.FillSource fed with the gateway's executions.
+type fillSource struct {
+	mu       *sync.Mutex
+	closed   bool
+	messages chan *sfclient.FillMessage
+	logger   *slog.Logger
+}
+
+func (s *fillSource) Listen() (<-chan *sfclient.FillMessage, error) {
+	return s.messages, nil
+}
+
+func (s *fillSource) send(msg *sfclient.FillMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	// Like the hub, never let a slow reader stall matching
+	select {
+	case s.messages <- msg:
+	default:
+		s.logger.Warn("paper: dropped fill", sfclient.LogOrderID, msg.Order.ID)
+	}
+}
+
+func (s *fillSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.messages)
+	}
+}
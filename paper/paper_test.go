@@ -0,0 +1,194 @@
+package paper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+const (
+	testAccount                 = "EXB123456"
+	testVenue   sfclient.Venue  = "TESTEX"
+	testSymbol  sfclient.Symbol = "FOOBAR"
+)
+
+func newGateway(t *testing.T, cfg Config) (*Gateway, *sftest.Market, <-chan *sfclient.FillMessage) {
+	m := sftest.NewMarket()
+	m.SetBook(&sfclient.StockOrderBookResponse{
+		APIResponse: sfclient.APIResponse{OK: true},
+		Bids:        []sfclient.AskBid{{Price: 99, Quantity: 10, IsBuy: true}, {Price: 98, Quantity: 20, IsBuy: true}},
+		Asks:        []sfclient.AskBid{{Price: 101, Quantity: 10}, {Price: 102, Quantity: 20}},
+	})
+
+	g, err := New(m, testAccount, testVenue, testSymbol, cfg)
+	if err != nil {
+		t.Fatalf("error creating gateway: %v", err)
+	}
+	t.Cleanup(g.Close)
+
+	fs, err := g.StockFillSource(testAccount, testVenue, testSymbol)
+	if err != nil {
+		t.Fatalf("error creating fill source: %v", err)
+	}
+	fills, _ := fs.Listen()
+	return g, m, fills
+}
+
+func nextFill(t *testing.T, fills <-chan *sfclient.FillMessage) *sfclient.FillMessage {
+	select {
+	case msg := <-fills:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no fill received")
+		return nil
+	}
+}
+
+func TestIncomingWalksBook(t *testing.T) {
+	g, m, fills := newGateway(t, Config{})
+
+	resp, err := g.BuyOrder(testAccount, testVenue, testSymbol, 102, 15, sfclient.TypeLimit)
+	if err != nil {
+		t.Fatalf("error buying: %v", err)
+	}
+
+	if resp.TotalFilled != 15 || resp.Open || len(resp.Fills) != 2 {
+		t.Fatalf("expected 15 filled over two levels, got %+v", resp)
+	}
+	if resp.Fills[0].Price != 101 || resp.Fills[1].Price != 102 || resp.Fills[1].Quantity != 5 {
+		t.Errorf("unexpected fills: %+v", resp.Fills)
+	}
+
+	if f := nextFill(t, fills); f.Price != 101 || f.Filled != 10 || f.IncomingID != resp.ID {
+		t.Errorf("unexpected first fill: %+v", f)
+	}
+	if f := nextFill(t, fills); f.Price != 102 || f.Filled != 5 || !f.IncomingComplete {
+		t.Errorf("unexpected second fill: %+v", f)
+	}
+
+	p := g.Portfolio()
+	if p.Position != 15 || p.Cash != -(10*101+5*102) {
+		t.Errorf("unexpected portfolio: %+v", p)
+	}
+
+	// The liquidity taken is gone until the book is refreshed
+	resp, _ = g.BuyOrder(testAccount, testVenue, testSymbol, 101, 5, sfclient.TypeImmediateOrCancel)
+	if resp.TotalFilled != 0 || resp.Open {
+		t.Errorf("expected IOC at 101 to be killed, got %+v", resp)
+	}
+
+	// The market's own book is not consumed
+	book, _ := m.StockOrderBook(testVenue, testSymbol)
+	if book.Asks[0].Quantity != 10 || book.Asks[1].Quantity != 20 {
+		t.Errorf("expected the market's book untouched, got %+v", book.Asks)
+	}
+
+	g.Close()
+	g.Close()
+}
+
+func TestFillOrKill(t *testing.T) {
+	g, _, _ := newGateway(t, Config{})
+
+	resp, _ := g.SellOrder(testAccount, testVenue, testSymbol, 98, 31, sfclient.TypeFillOrKill)
+	if resp.TotalFilled != 0 || resp.Open {
+		t.Errorf("expected FOK for more than the book to be killed, got %+v", resp)
+	}
+
+	resp, _ = g.SellOrder(testAccount, testVenue, testSymbol, 98, 30, sfclient.TypeFillOrKill)
+	if resp.TotalFilled != 30 {
+		t.Errorf("expected FOK to fill completely, got %+v", resp)
+	}
+	if p := g.Portfolio(); p.Position != -30 || p.Sold != 30 {
+		t.Errorf("unexpected portfolio: %+v", p)
+	}
+}
+
+func TestRestingFilledByTicks(t *testing.T) {
+	g, m, fills := newGateway(t, Config{})
+
+	resp, _ := g.BuyOrder(testAccount, testVenue, testSymbol, 100, 10, sfclient.TypeLimit)
+	if !resp.Open || resp.TotalFilled != 0 {
+		t.Fatalf("expected order to rest, got %+v", resp)
+	}
+
+	now := time.Now()
+	tick := func(bid, ask, last, lastSize int, at time.Time) {
+		msg := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+		msg.Quote = sfclient.StockState{Bid: bid, BidSize: 10, Ask: ask, AskSize: 10, Last: last, LastSize: lastSize, LastTrade: at}
+		m.PushTick(msg)
+	}
+
+	// A trade at our price does not fill us without FillAtTouch, a trade
+	// through it does.
+	tick(100, 101, 100, 5, now)
+	tick(99, 101, 99, 4, now.Add(time.Second))
+
+	f := nextFill(t, fills)
+	if f.Filled != 4 || f.Price != 100 || f.StandingID != resp.ID {
+		t.Errorf("unexpected fill: %+v", f)
+	}
+
+	// The ask dropping to our price takes the rest
+	tick(99, 100, 99, 4, now.Add(time.Second))
+	f = nextFill(t, fills)
+	if f.Filled != 6 || !f.StandingComplete || f.Order.Open {
+		t.Errorf("unexpected fill: %+v", f)
+	}
+
+	status, err := g.OrderStatus(testVenue, testSymbol, resp.ID)
+	if err != nil || status.TotalFilled != 10 || status.Open {
+		t.Errorf("unexpected status: %+v %v", status, err)
+	}
+}
+
+func TestRestingSharesTicks(t *testing.T) {
+	g, m, fills := newGateway(t, Config{})
+
+	first, _ := g.BuyOrder(testAccount, testVenue, testSymbol, 100, 8, sfclient.TypeLimit)
+	second, _ := g.BuyOrder(testAccount, testVenue, testSymbol, 100, 8, sfclient.TypeLimit)
+
+	// 10 offered at our price go to the first bid, then the second, and are
+	// not offered again by the same quote
+	msg := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	msg.Quote = sfclient.StockState{Bid: 99, BidSize: 10, Ask: 100, AskSize: 10}
+	m.PushTick(msg)
+	m.PushTick(msg)
+
+	if f := nextFill(t, fills); f.StandingID != first.ID || f.Filled != 8 {
+		t.Errorf("unexpected first fill: %+v", f)
+	}
+	if f := nextFill(t, fills); f.StandingID != second.ID || f.Filled != 2 {
+		t.Errorf("unexpected second fill: %+v", f)
+	}
+	select {
+	case f := <-fills:
+		t.Errorf("unexpected fill from a repeated quote: %+v", f)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if p := g.Portfolio(); p.Position != 10 {
+		t.Errorf("expected 10 bought, got %+v", p)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	g, _, _ := newGateway(t, Config{})
+
+	resp, _ := g.SellOrder(testAccount, testVenue, testSymbol, 110, 10, sfclient.TypeLimit)
+	cr, err := g.CancelOrder(testVenue, testSymbol, resp.ID)
+	if err != nil || cr.Open || cr.Quantity != 0 {
+		t.Errorf("unexpected cancel response: %+v %v", cr, err)
+	}
+
+	all, _ := g.StockOrdersStatus(testAccount, testVenue, testSymbol)
+	if len(all.Orders) != 1 || all.Orders[0].Open {
+		t.Errorf("unexpected orders: %+v", all.Orders)
+	}
+
+	if _, err := g.BuyOrder(testAccount, testVenue, "OTHER", 100, 1, sfclient.TypeLimit); err == nil {
+		t.Error("expected error trading another stock")
+	}
+}
@@ -1,7 +1,7 @@
 package quantest
 
 import (
-	"sort"
+	"slices"
 )
 
 /*
@@ -15,33 +15,106 @@ import (
 
 const bufSize = 1024
 
-type CKMS struct {
-	count       int
-	compressIdx int
-	nodeList
-	buf       [bufSize]int
+// rankMode selects the error function of a CKMS summary.
+type rankMode int
+
+const (
+	// targeted bounds the error at a set of chosen quantiles
+	targeted rankMode = iota
+
+	// lowBiased bounds the error at rank r by epsilon*r, so the lowest
+	// quantiles are the most accurate
+	lowBiased
+
+	// highBiased bounds the error at rank r by epsilon*(n-r), so the
+	// highest quantiles are the most accurate
+	highBiased
+)
+
+// CKMS is a quantile summary. The summary is a slice of items sorted by
+// value; inserts are buffered and merged into it in sorted batches, so the
+// cost of an insert is amortised over the whole batch.
+type CKMS[T Number] struct {
+	count   int
+	mode    rankMode
+	epsilon float64
+	samples []item[T]
+
+	// scratch is the previous summary, reused when merging in the next
+	// batch
+	scratch []item[T]
+
+	buf       [bufSize]T
 	bufCount  int
 	quantiles []Quantile
 }
 
-func NewCKMS(quantiles []Quantile) *CKMS {
-	return &CKMS{quantiles: quantiles}
+// NewCKMS creates a summary targeting quantiles, each estimated to within
+// its own error.
+func NewCKMS[T Number](quantiles []Quantile) *CKMS[T] {
+	return &CKMS[T]{quantiles: quantiles}
+}
+
+// NewLowBiasedCKMS creates a summary whose error at quantile q is at most
+// epsilon*q, for accurate estimates of the lowest quantiles without
+// choosing them in advance. It uses more memory the smaller the quantiles
+// it must resolve.
+func NewLowBiasedCKMS[T Number](epsilon float64) *CKMS[T] {
+	return &CKMS[T]{mode: lowBiased, epsilon: epsilon}
 }
 
-func (c *CKMS) allowableError(rank int) float64 {
-	// NOTE: according to CKMS, this should be count, not size, but this leads
-	// to error larger than the error bounds. Leaving it like this is
-	// essentially a HACK, and blows up memory, but does "work".
-	//size := c.count;
+// NewHighBiasedCKMS creates a summary whose error at quantile q is at most
+// epsilon*(1-q), for tails such as p99.9 latencies or the largest price
+// moves.
+func NewHighBiasedCKMS[T Number](epsilon float64) *CKMS[T] {
+	return &CKMS[T]{mode: highBiased, epsilon: epsilon}
+}
+
+// Count returns the number of samples inserted.
+func (c *CKMS[T]) Count() int {
+	return c.count + c.bufCount
+}
 
-	size := c.len()
-	minError := float64(size + 1)
+// Reset discards all samples, keeping the targets.
+func (c *CKMS[T]) Reset() {
+	*c = CKMS[T]{quantiles: c.quantiles, mode: c.mode, epsilon: c.epsilon}
+}
+
+func (c *CKMS[T]) clone() Estimator[T] {
+	cp := *c
+	cp.samples = slices.Clone(c.samples)
+	cp.scratch = nil
+	return &cp
+}
+
+// allowableError returns f(r, n) from the paper: the largest g+delta an
+// item of rank r may have while the estimates keep their error bounds.
+func (c *CKMS[T]) allowableError(rank int) float64 {
+	return c.allowableSpan(rank, rank)
+}
+
+// allowableSpan returns the least f(r, n) for ranks r from lo to hi. An
+// item covering those ranks must be no wider than this: f falls towards
+// each target, so checking only the lowest rank, as the paper does, lets an
+// item straddling a target be wider than the error allowed at the target.
+func (c *CKMS[T]) allowableSpan(lo, hi int) float64 {
+	switch c.mode {
+	case lowBiased:
+		return 2 * c.epsilon * float64(lo)
+	case highBiased:
+		return 2 * c.epsilon * float64(c.count-hi)
+	}
+
+	size := float64(c.count)
+	minError := size + 1
 	for _, q := range c.quantiles {
+		// f for a single target is least at the target itself
+		rank := min(max(q.q*size, float64(lo)), float64(hi))
 		var epsilon float64
-		if float64(rank) <= q.q*float64(size) {
-			epsilon = q.u * float64(size-rank)
+		if rank <= q.q*size {
+			epsilon = q.u * (size - rank)
 		} else {
-			epsilon = q.v * float64(rank)
+			epsilon = q.v * rank
 		}
 
 		if epsilon < minError {
@@ -52,111 +125,138 @@ func (c *CKMS) allowableError(rank int) float64 {
 	return minError
 }
 
-func (c *CKMS) Insert(v int) {
+func (c *CKMS[T]) Insert(v T) {
 	c.buf[c.bufCount] = v
 	c.bufCount++
 
 	if c.bufCount == bufSize {
-		c.insertBatch()
-		c.compress()
+		c.flush()
 	}
 }
 
-func (c *CKMS) insertBatch() {
-	if c.bufCount == 0 {
-		return
-	}
-	tmpArr := c.buf[0:c.bufCount]
-	sort.Sort(sort.IntSlice(tmpArr))
-
-	var start int
-	if c.len() == 0 {
-		item := NewItem(c.buf[0], 1, 0)
-		c.append(item)
-		start++
-		c.count++
+// flush merges any buffered samples into the summary.
+func (c *CKMS[T]) flush() {
+	if c.bufCount > 0 {
+		c.insertBatch()
+		c.compress()
 	}
+}
 
-	c.resetIterator()
-
-	curr := c.next()
-	for i := start; i < c.bufCount; i++ {
-		v := c.buf[i]
-		for c.nextIndex() < c.len() && curr.value < v {
-			curr = c.next()
-		}
-
-		// If we found the bigger item, back up so we insert just before
-		if curr.value > v {
-			c.prev()
+// insertBatch merges the sorted buffer into the summary in a single pass.
+func (c *CKMS[T]) insertBatch() {
+	batch := c.buf[0:c.bufCount]
+	slices.Sort(batch)
+
+	// The error allowed depends on the number of samples, which the batch
+	// is about to add to
+	c.count += len(batch)
+
+	merged := c.scratch[:0]
+	rank := 0
+	i := 0
+	for _, v := range batch {
+		for i < len(c.samples) && c.samples[i].value <= v {
+			rank += c.samples[i].g
+			merged = append(merged, c.samples[i])
+			i++
 		}
 
+		// A new minimum or maximum is known exactly. Otherwise the value
+		// ranks no higher than its successor can, which keeps the new item
+		// within the span its successor was allowed.
 		var delta int
-		if !(c.prevIndex() == 0 || c.nextIndex() == c.len()) {
-			delta = int(c.allowableError(c.nextIndex()))
+		if len(merged) > 0 && i < len(c.samples) {
+			delta = c.samples[i].g + c.samples[i].delta - 1
 		}
 
-		newItem := NewItem(v, 1, delta)
-		c.add(newItem)
-		c.count++
-		curr = newItem
+		merged = append(merged, item[T]{value: v, g: 1, delta: delta})
+		rank++
 	}
+	merged = append(merged, c.samples[i:]...)
 
+	c.scratch, c.samples = c.samples, merged
 	c.bufCount = 0
 }
 
-func (c *CKMS) compress() {
-	if c.len() < 2 {
+// compress merges each item into its successor where the combined item
+// still satisfies the error bound over the ranks it covers. The minimum and
+// maximum are kept.
+func (c *CKMS[T]) compress() {
+	if len(c.samples) < 3 {
 		return
 	}
 
-	c.resetIterator()
-	var prev item
-	curr := c.next()
-	removed := 0
-
-	for c.hasNext() {
-		prev = curr
-		curr = c.next()
-
-		if float64(prev.g+curr.g+curr.delta) <= c.allowableError(c.prevIndex()) {
-			// The following is does not actually update the value, doh
-			c.merge()
-			removed++
+	// rank is the lowest possible rank of samples[out], less its g
+	out := len(c.samples) - 1
+	rank := c.count - c.samples[out].g
+	for i := len(c.samples) - 2; i >= 1; i-- {
+		cur := c.samples[i]
+		rank -= cur.g
+
+		succ := &c.samples[out]
+		width := cur.g + succ.g + succ.delta
+		if float64(width) <= c.allowableSpan(rank, rank+width) {
+			succ.g += cur.g
+			continue
 		}
+		out--
+		c.samples[out] = cur
 	}
 
+	out--
+	c.samples[out] = c.samples[0]
+	c.samples = append(c.samples[:0], c.samples[out:]...)
 }
 
-func (c *CKMS) Query(quantile float64) int {
-	// clear the buffer
-	c.insertBatch()
-	c.compress()
+// items returns the summary, smallest value first, after flushing the
+// buffer. It must not be modified.
+func (c *CKMS[T]) items() []item[T] {
+	c.flush()
+	return c.samples
+}
 
-	if c.len() == 0 {
-		panic("no samples present")
+// setItems replaces the summary with items, smallest value first.
+func (c *CKMS[T]) setItems(items []item[T]) {
+	c.samples = items
+	c.scratch = nil
+}
+
+// Merge adds the samples summarised by other, which must have been created
+// with the same targets, so that c summarises both streams. other is left
+// unchanged.
+func (c *CKMS[T]) Merge(other *CKMS[T]) error {
+	if c.mode != other.mode || c.epsilon != other.epsilon || !sameQuantiles(c.quantiles, other.quantiles) {
+		return ErrIncompatible
 	}
 
-	rankMin := 0
-	desired := float64(c.count) * quantile
+	items := mergeItems(c.items(), c.count, other.items(), other.count)
+	c.setItems(items)
+	c.count += other.count
+	c.compress()
+	return nil
+}
 
-	c.resetIterator()
+// Query returns an estimate of the quantile, or 0 if no samples have been
+// inserted.
+func (c *CKMS[T]) Query(quantile float64) T {
+	c.flush()
 
-	curr := c.next()
-	var prev item
-	for c.hasNext() {
-		prev = curr
-		curr = c.next()
+	if len(c.samples) == 0 {
+		return 0
+	}
 
-		rankMin += prev.g
-		a := float64(rankMin+curr.g+curr.delta)
-		d := desired+(c.allowableError(int(desired))/2)
+	desired := float64(c.count) * quantile
+	bound := desired + c.allowableError(int(desired))/2
 
-		if a > d {
+	rankMin := 0
+	for i := 1; i < len(c.samples); i++ {
+		prev, curr := c.samples[i-1], c.samples[i]
+		rankMin += prev.g
+		if float64(rankMin+curr.g+curr.delta) > bound {
 			return prev.value
 		}
 	}
 
 	// Edge case of wanting max value
-	return c.get(c.len() - 1).value
+	return c.samples[len(c.samples)-1].value
 }
@@ -0,0 +1,345 @@
+package quantest
+
+import (
+	"bytes"
+	"slices"
+)
+
+// listCKMS is the CKMS implementation from before the summary moved from a
+// doubly linked list to a slice, kept unchanged apart from the type
+// parameter so benchmarks can compare the two.
+type listCKMS[T Number] struct {
+	count       int
+	compressIdx int
+	nodeList[T]
+	buf       [bufSize]T
+	bufCount  int
+	quantiles []Quantile
+}
+
+func newListCKMS[T Number](quantiles []Quantile) *listCKMS[T] {
+	return &listCKMS[T]{quantiles: quantiles}
+}
+
+func (c *listCKMS[T]) allowableError(rank int) float64 {
+	// NOTE: according to CKMS, this should be count, not size, but this leads
+	// to error larger than the error bounds. Leaving it like this is
+	// essentially a HACK, and blows up memory, but does "work".
+	//size := c.count;
+
+	size := c.len()
+	minError := float64(size + 1)
+	for _, q := range c.quantiles {
+		var epsilon float64
+		if float64(rank) <= q.q*float64(size) {
+			epsilon = q.u * float64(size-rank)
+		} else {
+			epsilon = q.v * float64(rank)
+		}
+
+		if epsilon < minError {
+			minError = epsilon
+		}
+	}
+
+	return minError
+}
+
+func (c *listCKMS[T]) Insert(v T) {
+	c.buf[c.bufCount] = v
+	c.bufCount++
+
+	if c.bufCount == bufSize {
+		c.insertBatch()
+		c.compress()
+	}
+}
+
+func (c *listCKMS[T]) insertBatch() {
+	if c.bufCount == 0 {
+		return
+	}
+	tmpArr := c.buf[0:c.bufCount]
+	slices.Sort(tmpArr)
+
+	var start int
+	if c.len() == 0 {
+		item := NewItem(c.buf[0], 1, 0)
+		c.append(item)
+		start++
+		c.count++
+	}
+
+	c.resetIterator()
+
+	curr := c.next()
+	for i := start; i < c.bufCount; i++ {
+		v := c.buf[i]
+		for c.nextIndex() < c.len() && curr.value < v {
+			curr = c.next()
+		}
+
+		// If we found the bigger item, back up so we insert just before
+		if curr.value > v {
+			c.prev()
+		}
+
+		var delta int
+		if !(c.prevIndex() == 0 || c.nextIndex() == c.len()) {
+			delta = int(c.allowableError(c.nextIndex()))
+		}
+
+		newItem := NewItem(v, 1, delta)
+		c.add(newItem)
+		c.count++
+		curr = newItem
+	}
+
+	c.bufCount = 0
+}
+
+func (c *listCKMS[T]) compress() {
+	if c.len() < 2 {
+		return
+	}
+
+	c.resetIterator()
+	var prev item[T]
+	curr := c.next()
+	removed := 0
+
+	for c.hasNext() {
+		prev = curr
+		curr = c.next()
+
+		if float64(prev.g+curr.g+curr.delta) <= c.allowableError(c.prevIndex()) {
+			// The following is does not actually update the value, doh
+			c.merge()
+			removed++
+		}
+	}
+
+}
+
+func (c *listCKMS[T]) Query(quantile float64) T {
+	// clear the buffer
+	c.insertBatch()
+	c.compress()
+
+	if c.len() == 0 {
+		panic("no samples present")
+	}
+
+	rankMin := 0
+	desired := float64(c.count) * quantile
+
+	c.resetIterator()
+
+	curr := c.next()
+	var prev item[T]
+	for c.hasNext() {
+		prev = curr
+		curr = c.next()
+
+		rankMin += prev.g
+		a := float64(rankMin + curr.g + curr.delta)
+		d := desired + (c.allowableError(int(desired)) / 2)
+
+		if a > d {
+			return prev.value
+		}
+	}
+
+	// Edge case of wanting max value
+	return c.get(c.len() - 1).value
+}
+
+type node[T Number] struct {
+	item[T]
+	next *node[T]
+	prev *node[T]
+}
+
+func (n *node[T]) insertBefore(in *node[T]) {
+	in.next = n
+	if n != nil {
+		in.prev = n.prev
+		in.next.prev = in
+
+		if in.prev != nil {
+			in.prev.next = in
+		}
+	}
+}
+
+func (n *node[T]) insertAfter(in *node[T]) {
+	in.prev = n
+	if n != nil {
+		in.next = n.next
+		in.prev.next = in
+		if in.next != nil {
+			in.next.prev = in
+
+		}
+	}
+}
+
+func (n *node[T]) remove() *node[T] {
+	// assume non nil
+	if n.prev != nil {
+		n.prev.next = n.next
+	}
+
+	if n.next != nil {
+		n.next.prev = n.prev
+	}
+
+	return n.prev
+}
+
+type nodeList[T Number] struct {
+	head      *node[T]
+	curr      *node[T]
+	currIndex int
+	total     int
+}
+
+func (n nodeList[T]) String() string {
+	buf := bytes.NewBufferString("[")
+	for curr := n.head; curr != nil; curr = curr.next {
+		buf.WriteString(curr.item.String() + ", ")
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+func (s *nodeList[T]) insert(i int, it item[T]) {
+	curr := s.head
+	for j := 0; j < i; j++ {
+		curr = curr.next
+	}
+
+	newNode := &node[T]{item: it}
+	curr.insertBefore(newNode)
+
+	if i == 0 {
+		s.head = newNode
+	}
+	s.total++
+}
+
+func (s *nodeList[T]) remove(i int) {
+	curr := s.head
+	for j := 0; j < i; j++ {
+		curr = curr.next
+	}
+	curr.remove()
+
+	if i == 0 {
+		s.head = s.head.next
+	}
+	s.total--
+}
+
+func (s *nodeList[T]) get(i int) item[T] {
+	curr := s.head
+	for j := 0; j < i; j++ {
+		curr = curr.next
+	}
+
+	return curr.item
+}
+
+func (s *nodeList[T]) next() item[T] {
+	if s.curr == nil {
+		s.curr = s.head
+	} else {
+		s.curr = s.curr.next
+		s.currIndex++
+	}
+
+	return s.curr.item
+}
+
+func (s *nodeList[T]) prev() item[T] {
+	if s.curr == s.head {
+		s.curr = nil
+		return item[T]{}
+	}
+	s.curr = s.curr.prev
+	s.currIndex--
+	return s.curr.item
+}
+
+func (s *nodeList[T]) nextIndex() int {
+	if s.curr == nil {
+		return 0
+	}
+	return s.currIndex + 1
+}
+
+func (s *nodeList[T]) prevIndex() int {
+	return s.currIndex - 1
+}
+
+func (s *nodeList[T]) len() int {
+	return s.total
+}
+
+func (s *nodeList[T]) add(it item[T]) {
+	s.total++
+	newNode := &node[T]{item: it}
+	if s.curr == nil {
+		s.head.insertBefore(newNode)
+		s.head = newNode
+		return
+	}
+	s.curr.insertAfter(newNode)
+}
+
+func (s *nodeList[T]) resetIterator() {
+	s.currIndex = 0
+	s.curr = nil
+}
+
+// Ths is pretty poorly performing
+func (s *nodeList[T]) append(it item[T]) {
+	newNode := &node[T]{item: it}
+	s.total++
+	if s.head == nil {
+		s.head = newNode
+		return
+	}
+
+	var last *node[T]
+	curr := s.head
+	for curr != nil {
+		last = curr
+		curr = curr.next
+	}
+
+	last.next = newNode
+	newNode.prev = last
+}
+
+func (s *nodeList[T]) hasNext() bool {
+	if s.curr == nil {
+		// has not started iterating
+		return s.head != nil
+	}
+
+	return s.curr.next != nil
+}
+
+func (s *nodeList[T]) merge() {
+	s.curr.g += s.curr.prev.g
+	s.prev()
+	s.total--
+	if s.curr == s.head {
+		s.head = s.curr.next
+		s.head.prev = nil
+	} else {
+		s.curr = s.curr.remove()
+	}
+	s.next()
+}
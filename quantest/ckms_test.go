@@ -3,6 +3,7 @@ package quantest
 import (
 	"math"
 	"math/rand"
+	"slices"
 	"sort"
 	"testing"
 )
@@ -17,12 +18,12 @@ func TestCKMS(t *testing.T) {
 		NewQuantile(0.99, 0.001),
 	}
 
-	estimator := NewCKMS(quantiles)
+	estimator := NewCKMS[int](quantiles)
 	samples := make([]int, windowSize, windowSize)
 
-	rand.Seed(0xDEADBEEF)
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
 	for i := 0; i < windowSize; i++ {
-		r := int(rand.Int31n(100000))
+		r := int(rng.Int31n(100000))
 		samples[i] = r
 		estimator.Insert(r)
 	}
@@ -33,9 +34,211 @@ func TestCKMS(t *testing.T) {
 		estimate := estimator.Query(quantile.q)
 		actual := samples[int(windowSize*quantile.q)-1]
 		err := math.Abs(float64(estimate-actual)) / float64(actual)
-		t.Logf("%s: estimate=%d, actual=%d, off=%.3f. memory saving=%.2f", quantile, estimate, actual, err, float64(estimator.len())/windowSize)
+		rankErr := rankError(samples, estimate, windowSize*quantile.q) / windowSize
+		t.Logf("%s: estimate=%d, actual=%d, off=%.3f, rank off=%.4f. memory saving=%.5f", quantile, estimate, actual, err, rankErr, float64(len(estimator.samples))/windowSize)
 		if err > quantile.errFrac {
 			t.Errorf("error in quantile estimation too large: max %.3f, got %.3f", quantile.errFrac, err)
 		}
+
+		// The bound CKMS gives is on rank, errFrac of the stream
+		if rankErr > quantile.errFrac {
+			t.Errorf("rank error in quantile estimation too large: max %.3f, got %.4f", quantile.errFrac, rankErr)
+		}
+	}
+}
+
+// TestCKMSRankError checks the rank error of the targets over many streams
+// of uniform and skewed samples. The error is allowed to reach errFrac of
+// the stream: at the median that is twice the value error TestCKMS asks of
+// uniform samples, and the estimator gets close to it.
+func TestCKMSRankError(t *testing.T) {
+	const (
+		windowSize = 200000
+		streams    = 20
+	)
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+		NewQuantile(0.95, 0.005),
+		NewQuantile(0.99, 0.001),
+	}
+
+	dists := []struct {
+		name string
+		gen  func(rng *rand.Rand) int
+	}{
+		{"uniform", func(rng *rand.Rand) int { return int(rng.Int31n(100000)) }},
+		{"exponential", func(rng *rand.Rand) int { return int(rng.ExpFloat64() * 1000) }},
+	}
+
+	for _, dist := range dists {
+		worst := make([]float64, len(quantiles))
+		for seed := int64(0); seed < streams; seed++ {
+			rng := rand.New(rand.NewSource(seed))
+			estimator := NewCKMS[int](quantiles)
+			samples := make([]int, windowSize)
+			for i := range samples {
+				samples[i] = dist.gen(rng)
+				estimator.Insert(samples[i])
+			}
+			slices.Sort(samples)
+
+			for i, q := range quantiles {
+				err := rankError(samples, estimator.Query(q.q), windowSize*q.q) / windowSize
+				worst[i] = max(worst[i], err)
+				if err > q.errFrac {
+					t.Errorf("%s seed %d: rank error at %s too large: got %.4f", dist.name, seed, q, err)
+				}
+			}
+		}
+		for i, q := range quantiles {
+			t.Logf("%s: %s worst rank error %.4f", dist.name, q, worst[i])
+		}
+	}
+}
+
+func TestBiasedCKMS(t *testing.T) {
+	const (
+		windowSize = 1000000
+		epsilon    = 0.01
+	)
+
+	samples := latencies(windowSize)
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	low, high := NewLowBiasedCKMS[int](epsilon), NewHighBiasedCKMS[int](epsilon)
+	for _, v := range samples {
+		low.Insert(v)
+		high.Insert(v)
+	}
+
+	// The error at quantile q is within epsilon*q for the low biased
+	// summary and epsilon*(1-q) for the high biased one, for any q.
+	for _, q := range []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999, 0.9999} {
+		want := q * windowSize
+
+		lowErr := rankError(sorted, low.Query(q), want)
+		highErr := rankError(sorted, high.Query(q), want)
+		t.Logf("q=%.4f: low=%d off=%.0f, high=%d off=%.0f", q, low.Query(q), lowErr, high.Query(q), highErr)
+
+		if lowErr > epsilon*want+1 {
+			t.Errorf("low biased rank error at %.4f too large: max %.0f, got %.0f", q, epsilon*want, lowErr)
+		}
+		if highErr > epsilon*(windowSize-want)+1 {
+			t.Errorf("high biased rank error at %.4f too large: max %.0f, got %.0f", q, epsilon*(windowSize-want), highErr)
+		}
+	}
+	t.Logf("tuples: low=%d, high=%d", len(low.samples), len(high.samples))
+
+	if err := low.Merge(high); err != ErrIncompatible {
+		t.Errorf("expected ErrIncompatible merging different biases, got %v", err)
+	}
+}
+
+func TestCKMSInvariant(t *testing.T) {
+	estimator := NewCKMS[int](benchQuantiles)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := 0; i < 100000; i++ {
+		estimator.Insert(int(rng.Int31n(100000)))
+	}
+	estimator.flush()
+
+	// Every item covers a range of ranks no wider than the error allowed
+	// anywhere in that range, and the items account for every sample.
+	rank := 0
+	for i, it := range estimator.samples {
+		if i > 0 && it.value < estimator.samples[i-1].value {
+			t.Fatalf("summary out of order at %d: %v", i, estimator.samples[i-1:i+1])
+		}
+		width := it.g + it.delta
+		if allowed := estimator.allowableSpan(rank, rank+width); i > 0 && float64(width) > allowed {
+			t.Errorf("item %d %s exceeds error %.1f allowed at ranks %d to %d", i, it, allowed, rank, rank+width)
+		}
+		rank += it.g
+	}
+	if rank != estimator.count {
+		t.Errorf("summary accounts for %d samples, expected %d", rank, estimator.count)
+	}
+}
+
+// quantileEstimator is the part of Estimator the list based implementation
+// has, so the benchmarks can run against both.
+type quantileEstimator interface {
+	Insert(v int)
+	Query(quantile float64) int
+}
+
+var benchQuantiles = []Quantile{
+	NewQuantile(0.5, 0.05),
+	NewQuantile(0.9, 0.01),
+	NewQuantile(0.99, 0.001),
+}
+
+var benchImpls = []struct {
+	name string
+	new  func() quantileEstimator
+}{
+	{"slice", func() quantileEstimator { return NewCKMS[int](benchQuantiles) }},
+	{"list", func() quantileEstimator { return newListCKMS[int](benchQuantiles) }},
+}
+
+func BenchmarkCKMSInsert(b *testing.B) {
+	for _, impl := range benchImpls {
+		b.Run(impl.name, func(b *testing.B) {
+			rng := rand.New(rand.NewSource(0xDEADBEEF))
+			e := impl.new()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.Insert(int(rng.Int31n(100000)))
+			}
+		})
+	}
+}
+
+func BenchmarkCKMSQuery(b *testing.B) {
+	for _, impl := range benchImpls {
+		b.Run(impl.name, func(b *testing.B) {
+			rng := rand.New(rand.NewSource(0xDEADBEEF))
+			e := impl.new()
+			for i := 0; i < 1000000; i++ {
+				e.Insert(int(rng.Int31n(100000)))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.Query(0.99)
+			}
+		})
+	}
+}
+
+// BenchmarkCKMSMemory reports the size of the summary after a million
+// samples, in tuples and in bytes allocated to get there.
+func BenchmarkCKMSMemory(b *testing.B) {
+	for _, impl := range benchImpls {
+		b.Run(impl.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var tuples int
+			for i := 0; i < b.N; i++ {
+				rng := rand.New(rand.NewSource(0xDEADBEEF))
+				e := impl.new()
+				for j := 0; j < 1000000; j++ {
+					e.Insert(int(rng.Int31n(100000)))
+				}
+				e.Query(0.5)
+
+				switch e := e.(type) {
+				case *CKMS[int]:
+					tuples = len(e.samples)
+				case *listCKMS[int]:
+					tuples = e.len()
+				}
+			}
+			b.ReportMetric(float64(tuples), "tuples")
+		})
 	}
 }
@@ -0,0 +1,192 @@
+package quantest
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// shardSize is the number of samples a shard buffers before handing them to
+// the estimator.
+const shardSize = 256
+
+// Concurrent makes an Estimator safe for concurrent use. Inserts are spread
+// over shards, each buffering samples behind its own lock, so inserting
+// goroutines rarely contend with each other or with queries. A query takes
+// a snapshot of every shard's buffer and adds them to the estimator,
+// holding each shard's lock only long enough to swap its buffer, then
+// queries a copy of the estimator so inserts are not held up while it
+// runs. Estimators from this package are copied; any other is queried in
+// place, blocking inserts that fill a buffer until the query returns.
+type Concurrent[T Number] struct {
+	shards []shard[T]
+	next   atomic.Uint64
+
+	mu  sync.Mutex
+	est Estimator[T]
+
+	// gen is incremented by Reset, so buffers taken from the shards before
+	// it are not added after it
+	gen uint64
+
+	// dirty is set when est has changed since snap was copied from it
+	dirty bool
+
+	// spare is swapped for a shard's buffer when draining it
+	spare []T
+
+	// queryMu serialises queries of snap, which is taken before mu
+	queryMu sync.Mutex
+	snap    Estimator[T]
+}
+
+// cloner is implemented by the estimators that Concurrent can copy.
+type cloner[T Number] interface {
+	clone() Estimator[T]
+}
+
+type shard[T Number] struct {
+	mu  sync.Mutex
+	buf []T
+
+	// gen is the generation of the samples in buf
+	gen uint64
+
+	// keep shards on separate cache lines
+	_ [64]byte
+}
+
+// NewConcurrent wraps e, which must no longer be used directly, with the
+// given number of shards. One per inserting goroutine is plenty.
+func NewConcurrent[T Number](e Estimator[T], shards int) *Concurrent[T] {
+	c := &Concurrent[T]{
+		shards: make([]shard[T], max(1, shards)),
+		est:    e,
+		spare:  make([]T, 0, shardSize),
+	}
+	for i := range c.shards {
+		c.shards[i].buf = make([]T, 0, shardSize)
+	}
+	return c
+}
+
+func (c *Concurrent[T]) Insert(v T) {
+	s := &c.shards[c.next.Add(1)%uint64(len(c.shards))]
+	if full, gen := s.insert(v); full != nil {
+		c.handOff(full, gen)
+	}
+}
+
+// handOff adds a full buffer taken from a shard to the estimator, unless
+// the estimator has been reset since.
+func (c *Concurrent[T]) handOff(full []T, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.add(full)
+	}
+}
+
+// insert buffers v, returning the buffer and its generation once full.
+func (s *shard[T]) insert(v T) ([]T, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, v)
+	if len(s.buf) < shardSize {
+		return nil, 0
+	}
+	full := s.buf
+	s.buf = make([]T, 0, shardSize)
+	return full, s.gen
+}
+
+// add inserts samples into the estimator. Must be called with c.mu held.
+func (c *Concurrent[T]) add(samples []T) {
+	for _, v := range samples {
+		c.est.Insert(v)
+	}
+	c.dirty = c.dirty || len(samples) > 0
+}
+
+// drain adds every shard's buffered samples to the estimator. Must be
+// called with c.mu held.
+func (c *Concurrent[T]) drain() {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.buf, c.spare = c.spare[:0], s.buf
+		s.mu.Unlock()
+
+		c.add(c.spare)
+	}
+}
+
+// query calls fn with an estimator holding every sample inserted before
+// the call.
+func (c *Concurrent[T]) query(fn func(e Estimator[T])) {
+	c.queryMu.Lock()
+	defer c.queryMu.Unlock()
+
+	c.mu.Lock()
+	c.drain()
+	cl, ok := c.est.(cloner[T])
+	if !ok {
+		defer c.mu.Unlock()
+		fn(c.est)
+		return
+	}
+	if c.snap == nil || c.dirty {
+		c.snap, c.dirty = cl.clone(), false
+	}
+	snap := c.snap
+	c.mu.Unlock()
+
+	fn(snap)
+}
+
+// Query returns an estimate of the quantile of every sample inserted before
+// the call.
+func (c *Concurrent[T]) Query(quantile float64) T {
+	var estimate T
+	c.query(func(e Estimator[T]) { estimate = e.Query(quantile) })
+	return estimate
+}
+
+// QueryAll returns estimates of several quantiles from the same snapshot,
+// so they are consistent with each other.
+func (c *Concurrent[T]) QueryAll(quantiles ...float64) []T {
+	estimates := make([]T, len(quantiles))
+	c.query(func(e Estimator[T]) {
+		for i, q := range quantiles {
+			estimates[i] = e.Query(q)
+		}
+	})
+	return estimates
+}
+
+// Count returns the number of samples inserted.
+func (c *Concurrent[T]) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.drain()
+	return c.est.Count()
+}
+
+// Reset discards all samples inserted before the call, including any in a
+// buffer an Insert has taken from its shard but not yet added.
+func (c *Concurrent[T]) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.buf = s.buf[:0]
+		s.gen = c.gen
+		s.mu.Unlock()
+	}
+	c.est.Reset()
+	c.dirty = true
+}
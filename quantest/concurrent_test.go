@@ -0,0 +1,140 @@
+package quantest
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrent(t *testing.T) {
+	const (
+		writers   = 4
+		perWriter = 100000
+	)
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.01),
+		NewQuantile(0.9, 0.01),
+	}
+	estimator := NewConcurrent[int](NewCKMS[int](quantiles), writers)
+
+	samples := make([][]int, writers)
+	for i := range samples {
+		r := rand.New(rand.NewSource(int64(i)))
+		samples[i] = make([]int, perWriter)
+		for j := range samples[i] {
+			samples[i][j] = int(r.Int31n(100000))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range samples {
+		wg.Add(1)
+		go func(s []int) {
+			defer wg.Done()
+			for _, v := range s {
+				estimator.Insert(v)
+			}
+		}(s)
+	}
+
+	// Read while the writers are inserting, as a strategy would
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			qs := estimator.QueryAll(0.5, 0.9)
+			if qs[0] > qs[1] {
+				t.Errorf("inconsistent snapshot: median %d above p90 %d", qs[0], qs[1])
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	var all []int
+	for _, s := range samples {
+		all = append(all, s...)
+	}
+	if n := estimator.Count(); n != len(all) {
+		t.Errorf("expected %d samples, got %d", len(all), n)
+	}
+	checkRanks(t, estimator.Query, quantiles, all)
+
+	estimator.Reset()
+	if n := estimator.Count(); n != 0 {
+		t.Errorf("expected no samples after reset, got %d", n)
+	}
+}
+
+func TestConcurrentQueryCopy(t *testing.T) {
+	estimator := NewConcurrent[int](NewCKMS[int]([]Quantile{NewQuantile(0.5, 0.01)}), 1)
+	for i := 0; i < shardSize; i++ {
+		estimator.Insert(i)
+	}
+
+	// A query runs on a copy, so a buffer can be handed off while it does
+	estimator.query(func(e Estimator[int]) {
+		added := make(chan struct{})
+		go func() {
+			defer close(added)
+			for i := 0; i < shardSize; i++ {
+				estimator.Insert(shardSize + i)
+			}
+		}()
+
+		select {
+		case <-added:
+		case <-time.After(5 * time.Second):
+			t.Fatal("insert blocked by a query")
+		}
+		if n := e.Count(); n != shardSize {
+			t.Errorf("expected the copy to hold %d samples, got %d", shardSize, n)
+		}
+	})
+
+	if n := estimator.Count(); n != 2*shardSize {
+		t.Errorf("expected %d samples, got %d", 2*shardSize, n)
+	}
+	if m := estimator.Query(0.5); m < shardSize-10 || m > shardSize+10 {
+		t.Errorf("expected a fresh copy with a median near %d, got %d", shardSize, m)
+	}
+}
+
+func TestConcurrentResetDropsTakenBuffer(t *testing.T) {
+	estimator := NewConcurrent[int](NewGK[int](0.01), 1)
+	s := &estimator.shards[0]
+
+	// Fill the shard and take its buffer, as an Insert does before adding
+	// it to the estimator
+	var full []int
+	var gen uint64
+	for i := 0; full == nil; i++ {
+		full, gen = s.insert(i)
+	}
+
+	estimator.Reset()
+	estimator.handOff(full, gen)
+
+	if n := estimator.Count(); n != 0 {
+		t.Errorf("expected samples taken before the reset to be dropped, got %d", n)
+	}
+
+	estimator.Insert(1)
+	if n := estimator.Count(); n != 1 {
+		t.Errorf("expected 1 sample after the reset, got %d", n)
+	}
+}
+
+func BenchmarkConcurrentInsert(b *testing.B) {
+	estimator := NewConcurrent[int](NewCKMS[int](benchQuantiles), 8)
+	b.RunParallel(func(pb *testing.PB) {
+		v := 0
+		for pb.Next() {
+			estimator.Insert(v % 100000)
+			v += 7919
+		}
+	})
+}
@@ -0,0 +1,353 @@
+package quantest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+// encodingVersion is the first byte of every binary encoding, so the format
+// can change without misreading old snapshots. Version 2 added the rank
+// mode of CKMS summaries; version 1 summaries are all targeted.
+const encodingVersion = 2
+
+var errInvalidEncoding = errors.New("quantest: invalid encoding")
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(v int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+func (e *encoder) int(v int) {
+	e.buf = binary.AppendVarint(e.buf, int64(v))
+}
+
+func (e *encoder) float(v float64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// isFloat reports whether T is a floating point type.
+func isFloat[T Number]() bool {
+	var half T = 1
+	half /= 2
+	return half != 0
+}
+
+// encodeItems writes items, with values as varints or, for floating point
+// types, as the bits of a float64.
+func encodeItems[T Number](e *encoder, items []item[T]) {
+	float := isFloat[T]()
+	e.uint(len(items))
+	for _, it := range items {
+		if float {
+			e.float(float64(it.value))
+		} else {
+			e.int(int(it.value))
+		}
+		e.uint(it.g)
+		e.uint(it.delta)
+	}
+}
+
+// decoder reads what encoder writes, remembering the first error so callers
+// only need to check once at the end.
+type decoder struct {
+	r       *bytes.Reader
+	version byte
+	err     error
+}
+
+func newDecoder(data []byte) *decoder {
+	d := &decoder{r: bytes.NewReader(data)}
+	v, err := d.r.ReadByte()
+	if err != nil || v < 1 || v > encodingVersion {
+		d.err = errInvalidEncoding
+	}
+	d.version = v
+	return d
+}
+
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil || v > math.MaxInt {
+		d.err = errInvalidEncoding
+	}
+	return int(v)
+}
+
+func (d *decoder) int() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.err = errInvalidEncoding
+	}
+	return int(v)
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	var b [8]byte
+	if _, err := io.ReadFull(d.r, b[:]); err != nil {
+		d.err = errInvalidEncoding
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+}
+
+// length reads the length of a list whose elements take at least size
+// bytes, failing if there is not enough data left rather than allocating
+// for a corrupt length.
+func (d *decoder) length(size int) int {
+	n := d.uint()
+	if n > d.r.Len()/size {
+		d.err = errInvalidEncoding
+	}
+	if d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func decodeItems[T Number](d *decoder) []item[T] {
+	float := isFloat[T]()
+	items := make([]item[T], d.length(3))
+	for i := range items {
+		if float {
+			items[i].value = T(d.float())
+		} else {
+			items[i].value = T(d.int())
+		}
+		items[i].g, items[i].delta = d.uint(), d.uint()
+	}
+	return items
+}
+
+// done returns the first error, or an error if there is trailing data.
+func (d *decoder) done() error {
+	if d.err == nil && d.r.Len() > 0 {
+		d.err = errInvalidEncoding
+	}
+	return d.err
+}
+
+// validItems checks that items are sorted and account for count samples.
+func validItems[T Number](items []item[T], count int) bool {
+	total := 0
+	for i, it := range items {
+		if it.g < 0 || it.delta < 0 || (i > 0 && it.value < items[i-1].value) {
+			return false
+		}
+		total += it.g
+	}
+	return total == count
+}
+
+// jsonItem is an item encoded as [value, g, delta].
+type jsonItem[T Number] item[T]
+
+func (it jsonItem[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{it.value, it.g, it.delta})
+}
+
+func (it *jsonItem[T]) UnmarshalJSON(data []byte) error {
+	var fields [3]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields[0], &it.value); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields[1], &it.g); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields[2], &it.delta)
+}
+
+func toJSONItems[T Number](items []item[T]) []jsonItem[T] {
+	out := make([]jsonItem[T], len(items))
+	for i, it := range items {
+		out[i] = jsonItem[T](it)
+	}
+	return out
+}
+
+func fromJSONItems[T Number](in []jsonItem[T]) []item[T] {
+	items := make([]item[T], len(in))
+	for i, it := range in {
+		items[i] = item[T](it)
+	}
+	return items
+}
+
+type jsonQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Error    float64 `json:"error"`
+}
+
+type jsonCKMS[T Number] struct {
+	Bias      string         `json:"bias,omitempty"`
+	Epsilon   float64        `json:"epsilon,omitempty"`
+	Quantiles []jsonQuantile `json:"quantiles,omitempty"`
+	Count     int            `json:"count"`
+	Items     []jsonItem[T]  `json:"items"`
+}
+
+var biasNames = map[rankMode]string{
+	lowBiased:  "low",
+	highBiased: "high",
+}
+
+type jsonGK[T Number] struct {
+	Epsilon     float64       `json:"epsilon"`
+	CompactSize int           `json:"compactSize"`
+	Count       int           `json:"count"`
+	Items       []jsonItem[T] `json:"items"`
+}
+
+// MarshalBinary encodes the targets and summary, flushing any buffered
+// samples into the summary first.
+func (c *CKMS[T]) MarshalBinary() ([]byte, error) {
+	items := c.items()
+
+	e := &encoder{buf: []byte{encodingVersion}}
+	e.uint(int(c.mode))
+	e.float(c.epsilon)
+	e.uint(len(c.quantiles))
+	for _, q := range c.quantiles {
+		e.float(q.q)
+		e.float(q.errFrac)
+	}
+	e.uint(c.count)
+	encodeItems(e, items)
+	return e.buf, nil
+}
+
+// UnmarshalBinary replaces c with a sketch encoded by MarshalBinary.
+func (c *CKMS[T]) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data)
+	mode, epsilon := targeted, 0.0
+	if d.version >= 2 {
+		mode, epsilon = rankMode(d.uint()), d.float()
+	}
+	quantiles := make([]Quantile, d.length(16))
+	for i := range quantiles {
+		quantiles[i] = NewQuantile(d.float(), d.float())
+	}
+	count := d.uint()
+	items := decodeItems[T](d)
+	if err := d.done(); err != nil {
+		return err
+	}
+
+	return c.restore(mode, epsilon, quantiles, count, items)
+}
+
+func (c *CKMS[T]) MarshalJSON() ([]byte, error) {
+	items := c.items()
+
+	v := jsonCKMS[T]{
+		Bias:    biasNames[c.mode],
+		Epsilon: c.epsilon,
+		Count:   c.count,
+		Items:   toJSONItems(items),
+	}
+	for _, q := range c.quantiles {
+		v.Quantiles = append(v.Quantiles, jsonQuantile{Quantile: q.q, Error: q.errFrac})
+	}
+	return json.Marshal(v)
+}
+
+func (c *CKMS[T]) UnmarshalJSON(data []byte) error {
+	var v jsonCKMS[T]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	quantiles := make([]Quantile, len(v.Quantiles))
+	for i, q := range v.Quantiles {
+		quantiles[i] = NewQuantile(q.Quantile, q.Error)
+	}
+
+	mode := targeted
+	for m, name := range biasNames {
+		if v.Bias == name {
+			mode = m
+		}
+	}
+	if v.Bias != "" && mode == targeted {
+		return errInvalidEncoding
+	}
+
+	return c.restore(mode, v.Epsilon, quantiles, v.Count, fromJSONItems(v.Items))
+}
+
+func (c *CKMS[T]) restore(mode rankMode, epsilon float64, quantiles []Quantile, count int, items []item[T]) error {
+	if mode > highBiased || !validItems(items, count) {
+		return errInvalidEncoding
+	}
+
+	*c = CKMS[T]{mode: mode, epsilon: epsilon, quantiles: quantiles, count: count}
+	c.setItems(items)
+	return nil
+}
+
+func (g *GK[T]) MarshalBinary() ([]byte, error) {
+	e := &encoder{buf: []byte{encodingVersion}}
+	e.float(g.epsilon)
+	e.uint(g.compactSize)
+	e.uint(g.count)
+	encodeItems(e, g.sample)
+	return e.buf, nil
+}
+
+// UnmarshalBinary replaces g with a summary encoded by MarshalBinary.
+func (g *GK[T]) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data)
+	epsilon := d.float()
+	compactSize := d.uint()
+	count := d.uint()
+	items := decodeItems[T](d)
+	if err := d.done(); err != nil {
+		return err
+	}
+
+	return g.restore(epsilon, compactSize, count, items)
+}
+
+func (g *GK[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonGK[T]{
+		Epsilon:     g.epsilon,
+		CompactSize: g.compactSize,
+		Count:       g.count,
+		Items:       toJSONItems(g.sample),
+	})
+}
+
+func (g *GK[T]) UnmarshalJSON(data []byte) error {
+	var v jsonGK[T]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return g.restore(v.Epsilon, v.CompactSize, v.Count, fromJSONItems(v.Items))
+}
+
+func (g *GK[T]) restore(epsilon float64, compactSize, count int, items []item[T]) error {
+	if !validItems(items, count) {
+		return errInvalidEncoding
+	}
+
+	*g = GK[T]{epsilon: epsilon, compactSize: max(1, compactSize), count: count, sample: items}
+	return nil
+}
@@ -0,0 +1,85 @@
+package quantest
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*CKMS[int])(nil)
+	_ encoding.BinaryUnmarshaler = (*CKMS[int])(nil)
+	_ json.Marshaler             = (*CKMS[int])(nil)
+	_ json.Unmarshaler           = (*CKMS[int])(nil)
+	_ encoding.BinaryMarshaler   = (*GK[int])(nil)
+	_ encoding.BinaryUnmarshaler = (*GK[int])(nil)
+)
+
+func TestCKMSEncoding(t *testing.T) {
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+	}
+
+	estimator := NewCKMS[int](quantiles)
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := 0; i < 100000; i++ {
+		estimator.Insert(int(rng.Int31n(100000)))
+	}
+
+	bin, err := estimator.MarshalBinary()
+	if err != nil {
+		t.Fatalf("error marshalling: %v", err)
+	}
+	js, err := json.Marshal(estimator)
+	if err != nil {
+		t.Fatalf("error marshalling JSON: %v", err)
+	}
+
+	fromBin, fromJSON := &CKMS[int]{}, &CKMS[int]{}
+	if err := fromBin.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("error unmarshalling: %v", err)
+	}
+	if err := json.Unmarshal(js, fromJSON); err != nil {
+		t.Fatalf("error unmarshalling JSON: %v", err)
+	}
+
+	for _, restored := range []*CKMS[int]{fromBin, fromJSON} {
+		if !sameQuantiles(restored.quantiles, quantiles) || restored.count != estimator.count {
+			t.Errorf("restored sketch has quantiles %v and count %d", restored.quantiles, restored.count)
+		}
+		for _, q := range []float64{0.1, 0.5, 0.9} {
+			if got, want := restored.Query(q), estimator.Query(q); got != want {
+				t.Errorf("restored sketch estimates %.1f as %d, expected %d", q, got, want)
+			}
+		}
+
+		// A restored sketch keeps accepting samples
+		restored.Insert(1)
+		restored.Query(0.5)
+		if restored.count != estimator.count+1 {
+			t.Errorf("expected insert after restore to count")
+		}
+	}
+
+	if err := fromBin.UnmarshalBinary(bin[:len(bin)-1]); err == nil {
+		t.Error("expected error unmarshalling truncated data")
+	}
+}
+
+func TestGKEncoding(t *testing.T) {
+	g := NewGK[int](0.01)
+	for i := 0; i < 1000; i++ {
+		g.Insert(i)
+	}
+
+	bin, _ := g.MarshalBinary()
+	restored := &GK[int]{}
+	if err := restored.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("error unmarshalling: %v", err)
+	}
+	if restored.epsilon != g.epsilon || restored.count != g.count || len(restored.sample) != len(g.sample) {
+		t.Errorf("restored summary differs: %+v", restored)
+	}
+}
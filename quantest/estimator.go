@@ -0,0 +1,37 @@
+package quantest
+
+// Number is the set of types whose quantiles can be estimated: prices in
+// cents, spreads in basis points, latencies and returns.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Estimator is a streaming quantile estimator.
+type Estimator[T Number] interface {
+	// Insert adds a sample.
+	Insert(v T)
+
+	// Query returns an estimate of the quantile, between 0 and 1, of the
+	// samples inserted, or 0 if there are none.
+	Query(quantile float64) T
+
+	// Count returns the number of samples the estimate covers.
+	Count() int
+
+	// Reset discards all samples.
+	Reset()
+}
+
+var (
+	_ Estimator[int]     = (*CKMS[int])(nil)
+	_ Estimator[float64] = (*CKMS[float64])(nil)
+	_ Estimator[int]     = (*GK[int])(nil)
+	_ Estimator[float64] = (*GK[float64])(nil)
+	_ Estimator[int]     = (*Window[int])(nil)
+	_ Estimator[float64] = (*Window[float64])(nil)
+	_ Estimator[int]     = (*TDigest[int])(nil)
+	_ Estimator[float64] = (*TDigest[float64])(nil)
+	_ Estimator[int]     = (*Concurrent[int])(nil)
+)
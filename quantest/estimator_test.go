@@ -0,0 +1,67 @@
+package quantest
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEstimatorsFloat(t *testing.T) {
+	const windowSize = 100000
+
+	quantiles := []Quantile{
+		NewQuantile(0.1, 0.01),
+		NewQuantile(0.5, 0.01),
+		NewQuantile(0.9, 0.01),
+	}
+
+	estimators := map[string]Estimator[float64]{
+		"CKMS":   NewCKMS[float64](quantiles),
+		"GK":     NewGK[float64](0.01),
+		"Window": NewCountWindow[float64](quantiles, windowSize, 10),
+	}
+
+	// Spreads in basis points: small, and only distinguishable as floats
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	samples := make([]float64, windowSize)
+	for i := range samples {
+		samples[i] = 5 + rng.ExpFloat64()
+	}
+
+	for name, e := range estimators {
+		t.Run(name, func(t *testing.T) {
+			for _, v := range samples {
+				e.Insert(v)
+			}
+			if n := e.Count(); n != windowSize {
+				t.Errorf("expected count %d, got %d", windowSize, n)
+			}
+			checkRanks(t, e.Query, quantiles, samples)
+
+			e.Reset()
+			if n, v := e.Count(), e.Query(0.5); n != 0 || v != 0 {
+				t.Errorf("expected empty estimator after reset, got %d samples, median %v", n, v)
+			}
+
+			e.Insert(1.5)
+			if v := e.Query(0.5); v != 1.5 {
+				t.Errorf("expected estimator to be usable after reset, got median %v", v)
+			}
+		})
+	}
+}
+
+func TestFloatEncoding(t *testing.T) {
+	c := NewCKMS[float64]([]Quantile{NewQuantile(0.5, 0.01)})
+	for i := 0; i < 1000; i++ {
+		c.Insert(float64(i) / 4)
+	}
+
+	bin, _ := c.MarshalBinary()
+	restored := &CKMS[float64]{}
+	if err := restored.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("error unmarshalling: %v", err)
+	}
+	if got, want := restored.Query(0.5), c.Query(0.5); got != want {
+		t.Errorf("restored sketch estimates median as %v, expected %v", got, want)
+	}
+}
@@ -1,74 +1,130 @@
 package quantest
 
 import (
-	"log"
 	"math"
+	"slices"
+	"sort"
 )
 
-type GK struct {
-	epsilon     float64
-	count       int
+// GK is a Greenwald-Khanna summary. Unlike CKMS it gives the same error at
+// every quantile: the rank of an estimate is within epsilon*n of the rank
+// asked for, after n samples.
+type GK[T Number] struct {
+	epsilon float64
+	count   int
+
+	// compactSize is the number of inserts between compressions
 	compactSize int
-	sample      []item
+	sample      []item[T]
 }
 
-func (g *GK) Insert(val int) {
-	idx := 0
-
-	for _, item := range g.sample {
-		if item.value > val {
-			break
-		}
-		idx++
+func NewGK[T Number](epsilon float64) *GK[T] {
+	return &GK[T]{
+		epsilon:     epsilon,
+		compactSize: max(1, int(math.Floor(1/(2*epsilon)))),
 	}
+}
+
+// Count returns the number of samples inserted.
+func (g *GK[T]) Count() int {
+	return g.count
+}
+
+// Reset discards all samples.
+func (g *GK[T]) Reset() {
+	g.count = 0
+	g.sample = g.sample[:0]
+}
+
+func (g *GK[T]) clone() Estimator[T] {
+	cp := *g
+	cp.sample = slices.Clone(g.sample)
+	return &cp
+}
+
+// threshold is the largest g+delta a tuple may have, 2*epsilon*n.
+func (g *GK[T]) threshold() int {
+	return int(math.Floor(2 * g.epsilon * float64(g.count)))
+}
+
+func (g *GK[T]) Insert(val T) {
+	idx := sort.Search(len(g.sample), func(i int) bool {
+		return g.sample[i].value > val
+	})
 
+	// A new minimum or maximum is known exactly
 	delta := 0
 	if !(idx == 0 || idx == len(g.sample)) {
-		delta = int(math.Floor(2 * g.epsilon * float64(g.count)))
+		delta = max(0, g.threshold()-1)
 	}
 
-	newItem := item{value: val, g: 1, delta: delta}
-	g.sample = append(g.sample, item{})
+	g.sample = append(g.sample, item[T]{})
 	copy(g.sample[idx+1:], g.sample[idx:])
-	g.sample[idx] = newItem
+	g.sample[idx] = item[T]{value: val, g: 1, delta: delta}
+	g.count++
 
-	if len(g.sample) > g.compactSize {
+	if g.count%g.compactSize == 0 {
 		g.compress()
 	}
-	g.count++
 }
 
-func (g *GK) compress() {
-	removed := 0
-	for i := 0; i < len(g.sample)-1; i++ {
-		item := g.sample[i]
-		item1 := g.sample[i+1]
+// compress merges each tuple into its successor where the combined tuple
+// still satisfies the error bound. The minimum and maximum are kept.
+func (g *GK[T]) compress() {
+	if len(g.sample) < 3 {
+		return
+	}
 
-		if item.g+item1.g+item1.delta <= int(math.Floor(2*g.epsilon*float64(g.count))) {
-			item1.g += item.g
-			copy(g.sample[i:], g.sample[i+1:])
-			g.sample = g.sample[:len(g.sample)-1]
+	threshold := g.threshold()
+	out := len(g.sample) - 1
+	for i := len(g.sample) - 2; i >= 1; i-- {
+		succ := &g.sample[out]
+		if cur := g.sample[i]; cur.g+succ.g+succ.delta <= threshold {
+			succ.g += cur.g
+			continue
 		}
-
-		removed++
+		out--
+		g.sample[out] = g.sample[i]
 	}
 
-	log.Printf("removed %d", removed)
+	// The kept tuples now sit at out and after, behind the minimum
+	out--
+	g.sample[out] = g.sample[0]
+	g.sample = append(g.sample[:0], g.sample[out:]...)
 }
 
-func (g *GK) Query(quantile float64) int {
+// Query returns an estimate of the quantile, or 0 if no samples have been
+// inserted.
+func (g *GK[T]) Query(quantile float64) T {
+	if len(g.sample) == 0 {
+		return 0
+	}
+
+	desired := quantile * float64(g.count)
+	bound := desired + g.epsilon*float64(g.count)
+
 	var rankMin int
-	desired := int(quantile * float64(g.count))
 	for i := 1; i < len(g.sample); i++ {
-		prev := g.sample[i-1]
-		cur := g.sample[i]
-
+		prev, cur := g.sample[i-1], g.sample[i]
 		rankMin += prev.g
 
-		if rankMin+cur.g+cur.delta > int(desired+int(2*g.epsilon*float64(g.count))) {
+		if float64(rankMin+cur.g+cur.delta) > bound {
 			return prev.value
 		}
 	}
 
 	return g.sample[len(g.sample)-1].value
 }
+
+// Merge adds the samples summarised by other, which must have the same
+// epsilon, so that g summarises both streams.
+func (g *GK[T]) Merge(other *GK[T]) error {
+	if g.epsilon != other.epsilon {
+		return ErrIncompatible
+	}
+
+	g.sample = mergeItems(g.sample, g.count, other.sample, other.count)
+	g.count += other.count
+	g.compress()
+	return nil
+}
@@ -0,0 +1,96 @@
+package quantest
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGK(t *testing.T) {
+	const (
+		windowSize = 1000000
+		epsilon    = 0.001
+	)
+
+	// GK has one error bound for every quantile; Quantile is only used to
+	// report it
+	quantiles := []Quantile{
+		NewQuantile(0.01, epsilon),
+		NewQuantile(0.1, epsilon),
+		NewQuantile(0.5, epsilon),
+		NewQuantile(0.9, epsilon),
+		NewQuantile(0.99, epsilon),
+	}
+
+	estimator := NewGK[int](epsilon)
+	samples := make([]int, windowSize)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := 0; i < windowSize; i++ {
+		r := int(rng.Int31n(100000))
+		samples[i] = r
+		estimator.Insert(r)
+	}
+
+	t.Logf("memory saving=%.4f", float64(len(estimator.sample))/windowSize)
+	checkRanks(t, estimator.Query, quantiles, samples)
+
+	// The summary stays within the space bound of the paper,
+	// 11/(2*epsilon) * log(2*epsilon*n)
+	if n := len(estimator.sample); n > 11*int(1/(2*epsilon))*14 {
+		t.Errorf("summary too large: %d tuples", n)
+	}
+}
+
+func TestGKSkewed(t *testing.T) {
+	const windowSize = 100000
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.01),
+		NewQuantile(0.99, 0.01),
+	}
+
+	estimator := NewGK[int](0.01)
+	samples := make([]int, windowSize)
+
+	// Sorted input is the worst case for insertion order
+	for i := range samples {
+		samples[i] = i * i
+		estimator.Insert(i * i)
+	}
+	checkRanks(t, estimator.Query, quantiles, samples)
+}
+
+func TestGKEmpty(t *testing.T) {
+	if v := NewGK[int](0.01).Query(0.5); v != 0 {
+		t.Errorf("expected 0 from an empty summary, got %d", v)
+	}
+}
+
+func TestGKMerge(t *testing.T) {
+	const windowSize = 200000
+
+	quantiles := []Quantile{
+		NewQuantile(0.1, 0.005),
+		NewQuantile(0.5, 0.005),
+		NewQuantile(0.9, 0.005),
+	}
+
+	a, b := NewGK[int](0.005), NewGK[int](0.005)
+	samples := make([]int, windowSize)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := range samples {
+		if i%2 == 0 {
+			samples[i] = int(rng.Int31n(100000))
+			a.Insert(samples[i])
+		} else {
+			samples[i] = 50000 + int(rng.Int31n(100000))
+			b.Insert(samples[i])
+		}
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("error merging: %v", err)
+	}
+	checkRanks(t, a.Query, quantiles, samples)
+}
@@ -0,0 +1,64 @@
+package quantest
+
+import "errors"
+
+// ErrIncompatible is returned when merging sketches built with different
+// targets, whose error guarantees cannot be combined.
+var ErrIncompatible = errors.New("quantest: sketches have different targets")
+
+// mergeItems combines two summaries, smallest value first, into a summary
+// of both streams. The bounds on the rank of each value in the combined
+// stream are the sums of its bounds in each summary, so the error of the
+// result is at most the sum of the errors of the inputs.
+func mergeItems[T Number](a []item[T], na int, b []item[T], nb int) []item[T] {
+	sa, sb := newSummary(a, na), newSummary(b, nb)
+	merged := make([]item[T], 0, len(a)+len(b))
+
+	prevMin := 0
+	add := func(value T, rankMin, rankMax int) {
+		merged = append(merged, item[T]{value: value, g: rankMin - prevMin, delta: rankMax - rankMin})
+		prevMin = rankMin
+	}
+
+	// On equal values items from a are placed first, so an item from a
+	// has the items of b with smaller values before it, and one from b has
+	// the items of a with values no greater.
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if j == len(b) || (i < len(a) && a[i].value <= b[j].value) {
+			lo, hi := sb.bounds(j)
+			add(a[i].value, sa.rankMin[i]+lo, sa.rankMax[i]+hi)
+			i++
+		} else {
+			lo, hi := sa.bounds(i)
+			add(b[j].value, sb.rankMin[j]+lo, sb.rankMax[j]+hi)
+			j++
+		}
+	}
+	return merged
+}
+
+// bounds returns bounds on the number of samples summarised that come
+// before the item at index i, or after all items if i is len(s.values).
+func (s summary[T]) bounds(i int) (lo, hi int) {
+	if i > 0 {
+		lo = s.rankMin[i-1]
+	}
+	hi = s.n
+	if i < len(s.values) {
+		hi = s.rankMax[i] - 1
+	}
+	return lo, max(lo, hi)
+}
+
+func sameQuantiles(a, b []Quantile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package quantest
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestCKMSMerge(t *testing.T) {
+	const windowSize = 300000
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+		NewQuantile(0.95, 0.005),
+	}
+
+	// Each sketch sees a different distribution, so neither alone is close
+	estimators := []*CKMS[int]{NewCKMS[int](quantiles), NewCKMS[int](quantiles), NewCKMS[int](quantiles)}
+	samples := make([]int, windowSize)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := range samples {
+		part := i % len(estimators)
+		r := part*50000 + int(rng.Int31n(100000))
+		samples[i] = r
+		estimators[part].Insert(r)
+	}
+
+	merged := estimators[0]
+	for _, e := range estimators[1:] {
+		if err := merged.Merge(e); err != nil {
+			t.Fatalf("error merging: %v", err)
+		}
+	}
+
+	if merged.count != windowSize {
+		t.Errorf("expected %d samples after merging, got %d", windowSize, merged.count)
+	}
+	checkRanks(t, merged.Query, quantiles, samples)
+}
+
+// checkRanks checks that the rank of each estimate returned by query is
+// within errFrac of the quantile wanted, the guarantee merging preserves.
+func checkRanks[T Number](t *testing.T, query func(float64) T, quantiles []Quantile, window []T) {
+	samples := append([]T(nil), window...)
+	slices.Sort(samples)
+	n := float64(len(samples))
+
+	for _, quantile := range quantiles {
+		estimate := query(quantile.q)
+		err := rankError(samples, estimate, quantile.q*n) / n
+
+		t.Logf("%s: estimate=%v, off=%.4f", quantile, estimate, err)
+		if err > quantile.errFrac {
+			t.Errorf("rank error in quantile estimation too large: max %.3f, got %.4f", quantile.errFrac, err)
+		}
+	}
+}
+
+// rankError returns how many samples away from rank want the ranks of
+// estimate in sorted are.
+func rankError[T Number](sorted []T, estimate T, want float64) float64 {
+	lo := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= estimate })
+	hi := sort.Search(len(sorted), func(i int) bool { return sorted[i] > estimate })
+	switch {
+	case want < float64(lo):
+		return float64(lo) - want
+	case want > float64(hi):
+		return want - float64(hi)
+	}
+	return 0
+}
@@ -0,0 +1,211 @@
+package quantest
+
+import (
+	"math"
+	"slices"
+)
+
+// TDigest is a merging t-digest (Dunning & Ertl). Samples are clustered
+// into centroids whose size is limited by the arcsine scale function, so
+// centroids near the extremes stay small and the tails are estimated far
+// more accurately than the middle: the rank error at quantile q is roughly
+// proportional to sqrt(q*(1-q))/compression. Unlike CKMS it interpolates
+// between centroids, so estimates need not be values that were inserted.
+type TDigest[T Number] struct {
+	compression float64
+	centroids   []centroid
+
+	// buf holds samples not yet merged into the centroids
+	buf []float64
+
+	count    int
+	min, max float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// NewTDigest creates a digest with the given compression, which bounds the
+// number of centroids to about compression/2. 100 is a common choice.
+func NewTDigest[T Number](compression float64) *TDigest[T] {
+	return &TDigest[T]{
+		compression: compression,
+		buf:         make([]float64, 0, 5*int(compression)),
+	}
+}
+
+func (d *TDigest[T]) Insert(v T) {
+	f := float64(v)
+	if d.count == 0 || f < d.min {
+		d.min = f
+	}
+	if d.count == 0 || f > d.max {
+		d.max = f
+	}
+	d.count++
+
+	d.buf = append(d.buf, f)
+	if len(d.buf) == cap(d.buf) {
+		d.flush()
+	}
+}
+
+// Count returns the number of samples inserted.
+func (d *TDigest[T]) Count() int {
+	return d.count
+}
+
+// Reset discards all samples.
+func (d *TDigest[T]) Reset() {
+	d.centroids = d.centroids[:0]
+	d.buf = d.buf[:0]
+	d.count = 0
+}
+
+func (d *TDigest[T]) clone() Estimator[T] {
+	cp := *d
+	cp.centroids = slices.Clone(d.centroids)
+	// The buffer is flushed when full, so keep its capacity
+	cp.buf = append(make([]float64, 0, cap(d.buf)), d.buf...)
+	return &cp
+}
+
+// qLimit returns the highest quantile a centroid starting at q may reach,
+// one unit further along the scale k(q) = compression/(2*pi) * asin(2q-1).
+func (d *TDigest[T]) qLimit(q float64) float64 {
+	k := d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+	k++
+	if k >= d.compression/4 {
+		return 1
+	}
+	return (math.Sin(2*math.Pi*k/d.compression) + 1) / 2
+}
+
+// flush merges the buffered samples into the centroids.
+func (d *TDigest[T]) flush() {
+	if len(d.buf) == 0 {
+		return
+	}
+
+	all := d.centroids
+	for _, v := range d.buf {
+		all = append(all, centroid{mean: v, weight: 1})
+	}
+	d.buf = d.buf[:0]
+	d.compress(all)
+}
+
+// compress replaces the centroids with all, sorted and merged as far as
+// the scale function allows.
+func (d *TDigest[T]) compress(all []centroid) {
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	total := 0.0
+	for _, c := range all {
+		total += c.weight
+	}
+
+	// Merging in place is safe as out never passes i
+	out := 0
+	soFar := 0.0
+	limit := total * d.qLimit(0)
+	for i := 1; i < len(all); i++ {
+		cur, next := &all[out], all[i]
+		if soFar+cur.weight+next.weight <= limit {
+			cur.weight += next.weight
+			cur.mean += (next.mean - cur.mean) * next.weight / cur.weight
+			continue
+		}
+
+		soFar += cur.weight
+		limit = total * d.qLimit(soFar/total)
+		out++
+		all[out] = next
+	}
+	d.centroids = all[:out+1]
+}
+
+// Query returns an estimate of the quantile, or 0 if no samples have been
+// inserted.
+func (d *TDigest[T]) Query(quantile float64) T {
+	d.flush()
+	if d.count == 0 {
+		return 0
+	}
+	return d.convert(d.query(quantile))
+}
+
+func (d *TDigest[T]) query(quantile float64) float64 {
+	if quantile <= 0 {
+		return d.min
+	}
+	if quantile >= 1 {
+		return d.max
+	}
+	cs := d.centroids
+
+	// Each centroid's samples are taken to be spread evenly around its
+	// mean, so its mean sits at the middle of its ranks. Interpolate
+	// between the means either side of the rank wanted, and between the
+	// outer means and the extremes.
+	target := quantile * float64(d.count)
+	if first := cs[0]; target < first.weight/2 {
+		return d.min + (first.mean-d.min)*target/(first.weight/2)
+	}
+
+	soFar := 0.0
+	for i := 0; i < len(cs)-1; i++ {
+		left, right := soFar+cs[i].weight/2, soFar+cs[i].weight+cs[i+1].weight/2
+		if target < right {
+			frac := (target - left) / (right - left)
+			return cs[i].mean + (cs[i+1].mean-cs[i].mean)*frac
+		}
+		soFar += cs[i].weight
+	}
+
+	last := cs[len(cs)-1]
+	start := float64(d.count) - last.weight/2
+	return last.mean + (d.max-last.mean)*(target-start)/(last.weight/2)
+}
+
+// convert rounds an estimate for integer types.
+func (d *TDigest[T]) convert(v float64) T {
+	if isFloat[T]() {
+		return T(v)
+	}
+	return T(math.Round(v))
+}
+
+// Merge adds the samples summarised by other, which must have the same
+// compression, so that d summarises both streams.
+func (d *TDigest[T]) Merge(other *TDigest[T]) error {
+	if d.compression != other.compression {
+		return ErrIncompatible
+	}
+	if other.count == 0 {
+		return nil
+	}
+
+	other.flush()
+	d.flush()
+	if d.count == 0 || other.min < d.min {
+		d.min = other.min
+	}
+	if d.count == 0 || other.max > d.max {
+		d.max = other.max
+	}
+	d.count += other.count
+
+	d.compress(append(d.centroids, other.centroids...))
+	return nil
+}
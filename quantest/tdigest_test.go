@@ -0,0 +1,104 @@
+package quantest
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// latencies returns n samples from a log-normal distribution, in
+// microseconds: mostly around a millisecond, with a long right tail.
+func latencies(n int) []int {
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	samples := make([]int, n)
+	for i := range samples {
+		samples[i] = int(1000 * math.Exp(rng.NormFloat64()))
+	}
+	return samples
+}
+
+// priceMoves returns n samples from a heavy tailed distribution of
+// relative price moves, in basis points.
+func priceMoves(n int) []float64 {
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	samples := make([]float64, n)
+	for i := range samples {
+		// Pareto with alpha 1.5, symmetric around zero
+		move := math.Pow(rng.Float64(), -1/1.5) - 1
+		if rng.Intn(2) == 0 {
+			move = -move
+		}
+		samples[i] = move
+	}
+	return samples
+}
+
+func TestTDigest(t *testing.T) {
+	const (
+		windowSize  = 1000000
+		compression = 100
+	)
+
+	samples := priceMoves(windowSize)
+	estimator := NewTDigest[float64](compression)
+	for _, v := range samples {
+		estimator.Insert(v)
+	}
+	t.Logf("centroids=%d", len(estimator.centroids))
+	if n := len(estimator.centroids); n > compression {
+		t.Errorf("expected at most %d centroids, got %d", compression, n)
+	}
+
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	// The error shrinks towards the tails. A centroid at q spans at most
+	// 2*pi*sqrt(q*(1-q))/compression of the samples, one unit of the
+	// arcsine scale, and interpolating within it is off by at most half that.
+	for _, q := range []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999, 0.9999} {
+		estimate := estimator.Query(q)
+		err := rankError(sorted, estimate, q*windowSize) / windowSize
+		allowed := math.Pi * math.Sqrt(q*(1-q)) / compression
+
+		t.Logf("q=%.4f: estimate=%.3f, actual=%.3f, off=%.5f", q, estimate, sorted[int(q*windowSize)], err)
+		if err > allowed {
+			t.Errorf("rank error at %.4f too large: max %.5f, got %.5f", q, allowed, err)
+		}
+	}
+
+	if min, max := estimator.Query(0), estimator.Query(1); min != sorted[0] || max != sorted[windowSize-1] {
+		t.Errorf("expected extremes %.3f and %.3f, got %.3f and %.3f", sorted[0], sorted[windowSize-1], min, max)
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	const windowSize = 200000
+
+	samples := latencies(windowSize)
+	a, b := NewTDigest[int](100), NewTDigest[int](100)
+	for i, v := range samples {
+		if i < windowSize/2 {
+			a.Insert(v)
+		} else {
+			b.Insert(v)
+		}
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("error merging: %v", err)
+	}
+	if a.Count() != windowSize {
+		t.Errorf("expected %d samples, got %d", windowSize, a.Count())
+	}
+
+	checkRanks(t, a.Query, []Quantile{
+		NewQuantile(0.5, 0.01),
+		NewQuantile(0.99, 0.001),
+		NewQuantile(0.999, 0.0005),
+	}, samples)
+
+	if err := a.Merge(NewTDigest[int](50)); err != ErrIncompatible {
+		t.Errorf("expected ErrIncompatible merging different compressions, got %v", err)
+	}
+}
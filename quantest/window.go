@@ -0,0 +1,230 @@
+package quantest
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Window estimates quantiles over a sliding window of the most recent
+// samples rather than the whole stream. The window is split into buckets,
+// each summarised by its own GK sketch; the oldest bucket is dropped whole
+// once every sample in it has left the window, and queries merge the
+// summaries of the remaining buckets.
+//
+// Error guarantees, for a window holding N samples:
+//   - each bucket bounds the rank of every value to within epsilon of its
+//     size, where epsilon is the smallest errFrac of the quantiles given.
+//     A bucket is summarised at every rank rather than just the targets, as
+//     the rank queried in the window can be any rank in a bucket, so the
+//     merged rank error is at most epsilon*N
+//   - the window is only trimmed a bucket at a time, so it may also include
+//     up to one bucket's worth of samples older than requested: n/buckets
+//     samples for count windows, d/buckets of time for time windows
+//
+// More buckets tighten the second bound at the cost of memory and query
+// time. Window is not safe for concurrent use.
+type Window[T Number] struct {
+	epsilon float64
+
+	// size is the window in samples for count windows, and width the
+	// samples per bucket. For time windows age and span are used instead.
+	size  int
+	width int
+	age   time.Duration
+	span  time.Duration
+
+	buckets []*bucket[T] // oldest first
+	total   int
+	newest  time.Time
+}
+
+type bucket[T Number] struct {
+	sketch *GK[T]
+	n      int
+	start  time.Time
+	end    time.Time
+}
+
+// NewCountWindow creates a window over the last n samples, split into the
+// given number of buckets.
+func NewCountWindow[T Number](quantiles []Quantile, n, buckets int) *Window[T] {
+	return &Window[T]{
+		epsilon: minErrFrac(quantiles),
+		size:    n,
+		width:   max(1, n/max(1, buckets)),
+	}
+}
+
+// NewTimeWindow creates a window over the samples inserted in the last d,
+// split into the given number of buckets. The window is measured back from
+// the newest sample rather than the wall clock, so recorded data can be
+// replayed through it; use Expire to advance it without a sample.
+func NewTimeWindow[T Number](quantiles []Quantile, d time.Duration, buckets int) *Window[T] {
+	return &Window[T]{
+		epsilon: minErrFrac(quantiles),
+		age:     d,
+		span:    max(1, d/time.Duration(max(1, buckets))),
+	}
+}
+
+func minErrFrac(quantiles []Quantile) float64 {
+	epsilon := 1.0
+	for _, q := range quantiles {
+		epsilon = min(epsilon, q.errFrac)
+	}
+	return epsilon
+}
+
+// Insert adds v, observed now.
+func (w *Window[T]) Insert(v T) {
+	w.InsertAt(time.Now(), v)
+}
+
+// InsertAt adds v, observed at t. A sample older than the newest already
+// inserted is treated as arriving at the newest time.
+func (w *Window[T]) InsertAt(t time.Time, v T) {
+	if t.Before(w.newest) {
+		t = w.newest
+	}
+	w.newest = t
+
+	var b *bucket[T]
+	if n := len(w.buckets); n > 0 {
+		b = w.buckets[n-1]
+	}
+	if b == nil || (w.age == 0 && b.n >= w.width) || (w.age > 0 && t.Sub(b.start) >= w.span) {
+		b = &bucket[T]{sketch: NewGK[T](w.epsilon), start: t}
+		w.buckets = append(w.buckets, b)
+	}
+
+	b.sketch.Insert(v)
+	b.n++
+	b.end = t
+	w.total++
+
+	w.expire()
+}
+
+// Expire drops the samples that are older than the window at now, for time
+// windows that may have stopped receiving samples. It has no effect on
+// count windows or if now is older than the newest sample.
+func (w *Window[T]) Expire(now time.Time) {
+	if now.After(w.newest) {
+		w.newest = now
+	}
+	w.expire()
+}
+
+func (w *Window[T]) expire() {
+	for len(w.buckets) > 0 {
+		oldest := w.buckets[0]
+		if w.age > 0 {
+			if !oldest.end.Before(w.newest.Add(-w.age)) {
+				break
+			}
+		} else if w.total-oldest.n < w.size {
+			break
+		}
+
+		w.buckets[0] = nil
+		w.buckets = w.buckets[1:]
+		w.total -= oldest.n
+	}
+}
+
+// Reset discards all samples.
+func (w *Window[T]) Reset() {
+	w.buckets = nil
+	w.total = 0
+	w.newest = time.Time{}
+}
+
+func (w *Window[T]) clone() Estimator[T] {
+	cp := *w
+	cp.buckets = make([]*bucket[T], len(w.buckets))
+	for i, b := range w.buckets {
+		bc := *b
+		bc.sketch = b.sketch.clone().(*GK[T])
+		cp.buckets[i] = &bc
+	}
+	return &cp
+}
+
+// Count returns the number of samples in the window.
+func (w *Window[T]) Count() int {
+	return w.total
+}
+
+// Query returns an estimate of the quantile over the window, or 0 if it is
+// empty.
+func (w *Window[T]) Query(quantile float64) T {
+	if w.total == 0 {
+		return 0
+	}
+
+	// The rank of a value in the window is the sum of its ranks in each
+	// bucket, each of which the bucket's summary bounds. Estimate it as the
+	// middle of the summed bounds, and pick the summary value whose
+	// estimate is closest to the rank wanted.
+	sums := make([]summary[T], len(w.buckets))
+	var candidates []T
+	for i, b := range w.buckets {
+		sums[i] = newSummary(b.sketch.sample, b.n)
+		candidates = append(candidates, sums[i].values...)
+	}
+	slices.Sort(candidates)
+
+	desired := quantile * float64(w.total)
+	best, bestErr := candidates[0], math.Inf(1)
+	for _, v := range candidates {
+		var lo, hi int
+		for _, s := range sums {
+			l, h := s.rank(v)
+			lo += l
+			hi += h
+		}
+
+		err := math.Abs(float64(lo+hi)/2 - desired)
+		if err < bestErr {
+			best, bestErr = v, err
+		}
+	}
+	return best
+}
+
+// summary is a CKMS summary with the bounds on the rank of each value
+// precomputed.
+type summary[T Number] struct {
+	values  []T
+	rankMin []int
+	rankMax []int
+	n       int
+}
+
+func newSummary[T Number](items []item[T], n int) summary[T] {
+	s := summary[T]{
+		values:  make([]T, len(items)),
+		rankMin: make([]int, len(items)),
+		rankMax: make([]int, len(items)),
+		n:       n,
+	}
+
+	rank := 0
+	for i, it := range items {
+		rank += it.g
+		s.values[i] = it.value
+		s.rankMin[i] = rank
+		s.rankMax[i] = rank + it.delta
+	}
+	return s
+}
+
+// rank returns bounds on the number of samples summarised that are no
+// greater than v.
+func (s summary[T]) rank(v T) (lo, hi int) {
+	return s.bounds(sort.Search(len(s.values), func(i int) bool {
+		return s.values[i] > v
+	}))
+}
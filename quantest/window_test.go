@@ -0,0 +1,140 @@
+package quantest
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// checkEstimates compares the estimates returned by query with the exact
+// quantiles of window, allowing the relative error TestCKMS does.
+func checkEstimates(t *testing.T, query func(float64) int, quantiles []Quantile, window []int) {
+	samples := append([]int(nil), window...)
+	sort.Ints(samples)
+
+	for _, quantile := range quantiles {
+		estimate := query(quantile.q)
+		actual := samples[int(float64(len(samples))*quantile.q)-1]
+		err := math.Abs(float64(estimate-actual)) / float64(actual)
+		t.Logf("%s: estimate=%d, actual=%d, off=%.3f", quantile, estimate, actual, err)
+		if err > quantile.errFrac {
+			t.Errorf("error in quantile estimation too large: max %.3f, got %.3f", quantile.errFrac, err)
+		}
+	}
+}
+
+func TestCountWindow(t *testing.T) {
+	const (
+		streamSize = 300000
+		windowSize = 100000
+		buckets    = 100
+	)
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+	}
+
+	estimator := NewCountWindow[int](quantiles, windowSize, buckets)
+	samples := make([]int, streamSize)
+
+	// The distribution drifts upwards, so an estimate over the whole stream
+	// would be well off.
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := range samples {
+		r := i/2 + int(rng.Int31n(100000))
+		samples[i] = r
+		estimator.Insert(r)
+	}
+
+	if n := estimator.Count(); n < windowSize || n >= windowSize+windowSize/buckets {
+		t.Errorf("expected between %d and %d samples, got %d", windowSize, windowSize+windowSize/buckets, n)
+	}
+	checkEstimates(t, estimator.Query, quantiles, samples[streamSize-windowSize:])
+}
+
+func TestTimeWindow(t *testing.T) {
+	const (
+		streamSize = 300000
+		windowSize = 100000
+	)
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+	}
+
+	// One sample a millisecond
+	start := time.Now()
+	estimator := NewTimeWindow[int](quantiles, windowSize*time.Millisecond, 100)
+	samples := make([]int, streamSize)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := range samples {
+		r := i/2 + int(rng.Int31n(100000))
+		samples[i] = r
+		estimator.InsertAt(start.Add(time.Duration(i)*time.Millisecond), r)
+	}
+
+	if n := estimator.Count(); n < windowSize || n > windowSize+windowSize/100 {
+		t.Errorf("expected between %d and %d samples, got %d", windowSize, windowSize+windowSize/100, n)
+	}
+	checkEstimates(t, estimator.Query, quantiles, samples[streamSize-windowSize:])
+
+	estimator.Expire(start.Add(time.Hour))
+	if n, v := estimator.Count(), estimator.Query(0.5); n != 0 || v != 0 {
+		t.Errorf("expected expired window to be empty, got %d samples, median %d", n, v)
+	}
+}
+
+// TestWindowShift checks the rank error of a window whose buckets hold
+// different distributions, so that the rank queried in the window falls at
+// an arbitrary rank in each bucket.
+func TestWindowShift(t *testing.T) {
+	const windowSize = 1000000
+
+	quantiles := []Quantile{
+		NewQuantile(0.5, 0.05),
+		NewQuantile(0.9, 0.01),
+	}
+
+	estimator := NewCountWindow[int](quantiles, windowSize, 2)
+	samples := make([]int, windowSize)
+
+	// The first half is spread widely and the second narrowly, so the
+	// median of the window is at the edge of the second half.
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := range samples {
+		r := int(rng.Int31n(1000000))
+		if i >= windowSize/2 {
+			r = 250000 + int(rng.Int31n(10000))
+		}
+		samples[i] = r
+		estimator.Insert(r)
+	}
+	sort.Ints(samples)
+
+	// Every rank is bounded by the smallest errFrac, not just the targets
+	const epsilon = 0.01
+	for i := 1; i < 20; i++ {
+		q := float64(i) / 20
+		estimate := estimator.Query(q)
+		lo := sort.SearchInts(samples, estimate)
+		hi := sort.SearchInts(samples, estimate+1)
+		desired := q * windowSize
+
+		// Any rank the estimate is at will do
+		err := 0.0
+		if desired < float64(lo) {
+			err = (float64(lo) - desired) / windowSize
+		} else if desired > float64(hi) {
+			err = (desired - float64(hi)) / windowSize
+		}
+		t.Logf("q=%.2f: estimate=%d, ranks %d-%d, off=%.4f", q, estimate, lo, hi, err)
+		if err > epsilon {
+			t.Errorf("rank error at q=%.2f too large: max %.3f, got %.4f", q, epsilon, err)
+		}
+	}
+}
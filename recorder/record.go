@@ -0,0 +1,79 @@
+// Package recorder persists market data seen through a StockHub as gzip
+// compressed, newline delimited JSON for later replay and analysis.
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+const (
+	TypeTick = "tick"
+	TypeFill = "fill"
+	TypeBook = "book"
+)
+
+// Record is a single line of a recording. Exactly one of Tick, Fill and Book
+// is set, according to Type.
+type Record struct {
+	Type string `json:"type"`
+
+	// Received is the local time the message was seen
+	Received time.Time `json:"recv"`
+
+	Tick *sfclient.TickMessage            `json:"tick,omitempty"`
+	Fill *sfclient.FillMessage            `json:"fill,omitempty"`
+	Book *sfclient.StockOrderBookResponse `json:"book,omitempty"`
+}
+
+// Time is the exchange timestamp of the record, falling back to the time it
+// was received when the exchange did not provide one.
+func (r *Record) Time() time.Time {
+	var t time.Time
+	switch {
+	case r.Tick != nil:
+		t = r.Tick.Quote.QuoteTime
+	case r.Fill != nil:
+		t = r.Fill.FilledAt
+	case r.Book != nil:
+		t = r.Book.Timestamp
+	}
+
+	if t.IsZero() {
+		return r.Received
+	}
+	return t
+}
+
+// Reader reads records from a single recording file.
+type Reader struct {
+	gz  *gzip.Reader
+	dec *json.Decoder
+}
+
+// NewReader returns a reader of the gzip compressed records in r.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+// Next returns the next record, or io.EOF when there are no more.
+func (r *Reader) Next() (*Record, error) {
+	rec := &Record{}
+	if err := r.dec.Decode(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
@@ -0,0 +1,239 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Config controls where recordings are written and how often they rotate.
+type Config struct {
+	// Dir is the directory files are written to. It is created if needed.
+	Dir string
+
+	// Prefix starts every file name. Register defaults it to VENUE-STOCK.
+	Prefix string
+
+	// MaxBytes rotates the file once this many uncompressed bytes have been
+	// written to it. 0 disables size based rotation.
+	MaxBytes int64
+
+	// MaxAge rotates the file once it has been open this long. 0 disables
+	// time based rotation.
+	MaxAge time.Duration
+
+	// SnapshotInterval is how often the full order book is fetched and
+	// recorded. 0 disables snapshots.
+	SnapshotInterval time.Duration
+}
+
+// Recorder writes every tick and fill from a StockHub, plus periodic order
+// book snapshots, to rotating files named
+// PREFIX-YYYYMMDDTHHMMSS.mmm-NNNNNN.ndjson.gz so that the files of a
+// recording sort in time order. The sequence number NNNNNN orders files
+// opened within the same millisecond.
+type Recorder struct {
+	cfg Config
+
+	tickch    chan *sfclient.TickMessage
+	fillch    chan *sfclient.FillMessage
+	done      chan struct{}
+	wg        *sync.WaitGroup
+	logger    *slog.Logger
+	closeOnce *sync.Once
+	closeErr  error
+
+	mu      *sync.Mutex
+	f       *os.File
+	gz      *gzip.Writer
+	written int64
+	opened  time.Time
+	seq     int
+}
+
+// New creates a recorder. Nothing is recorded until it is registered.
+func New(cfg Config) (*Recorder, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		cfg:       cfg,
+		tickch:    make(chan *sfclient.TickMessage, 1000),
+		fillch:    make(chan *sfclient.FillMessage, 1000),
+		done:      make(chan struct{}),
+		wg:        &sync.WaitGroup{},
+		logger:    slog.Default(),
+		closeOnce: &sync.Once{},
+		mu:        &sync.Mutex{},
+	}, nil
+}
+
+func (r *Recorder) Register(hub *sfclient.StockHub) {
+	if r.cfg.Prefix == "" {
+		r.cfg.Prefix = hub.Venue().String() + "-" + hub.Stock().String()
+	}
+	r.logger = hub.Logger()
+
+	hub.RegisterToTick(r.tickch)
+	hub.RegisterToFills(r.fillch)
+
+	r.wg.Add(1)
+	go r.run(hub)
+}
+
+func (r *Recorder) run(hub *sfclient.StockHub) {
+	defer r.wg.Done()
+
+	var snapshots <-chan time.Time
+	if r.cfg.SnapshotInterval > 0 {
+		t := time.NewTicker(r.cfg.SnapshotInterval)
+		defer t.Stop()
+		snapshots = t.C
+		r.snapshot(hub)
+	}
+
+	// Flush regularly so a crash loses at most a second of data
+	flush := time.NewTicker(time.Second)
+	defer flush.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			// Stop the hub sending before taking what it has already sent,
+			// so the end of the session is not lost
+			hub.UnregisterFromTick(r.tickch)
+			hub.UnregisterFromFills(r.fillch)
+			r.drain()
+			return
+		case msg := <-r.tickch:
+			r.write(&Record{Type: TypeTick, Received: time.Now(), Tick: msg})
+		case msg := <-r.fillch:
+			r.write(&Record{Type: TypeFill, Received: time.Now(), Fill: msg})
+		case <-snapshots:
+			r.snapshot(hub)
+		case <-flush.C:
+			r.mu.Lock()
+			if r.gz != nil {
+				if err := r.gz.Flush(); err != nil {
+					r.logger.Error("error flushing recording", "err", err)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// drain writes the ticks and fills waiting to be recorded.
+func (r *Recorder) drain() {
+	for {
+		select {
+		case msg := <-r.tickch:
+			r.write(&Record{Type: TypeTick, Received: time.Now(), Tick: msg})
+		case msg := <-r.fillch:
+			r.write(&Record{Type: TypeFill, Received: time.Now(), Fill: msg})
+		default:
+			return
+		}
+	}
+}
+
+func (r *Recorder) snapshot(hub *sfclient.StockHub) {
+	book, err := hub.OrderBook()
+	if err != nil {
+		r.logger.Warn("error fetching order book snapshot", "err", err)
+		return
+	}
+	r.write(&Record{Type: TypeBook, Received: time.Now(), Book: book})
+}
+
+func (r *Recorder) write(rec *Record) {
+	if err := r.Write(rec); err != nil {
+		r.logger.Error("error recording", "type", rec.Type, "err", err)
+	}
+}
+
+// Write appends rec to the current file, rotating first if required.
+func (r *Recorder) Write(rec *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil || r.shouldRotate() {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	n, err := r.gz.Write(b)
+	r.written += int64(n)
+	return err
+}
+
+func (r *Recorder) shouldRotate() bool {
+	if r.cfg.MaxBytes > 0 && r.written >= r.cfg.MaxBytes {
+		return true
+	}
+	return r.cfg.MaxAge > 0 && time.Since(r.opened) >= r.cfg.MaxAge
+}
+
+func (r *Recorder) rotate() error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.seq++
+	name := filepath.Join(r.cfg.Dir, fmt.Sprintf("%s-%s-%06d.ndjson.gz", r.cfg.Prefix, now.UTC().Format("20060102T150405.000"), r.seq))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	r.f = f
+	r.gz = gzip.NewWriter(f)
+	r.written = 0
+	r.opened = now
+	return nil
+}
+
+func (r *Recorder) closeFile() error {
+	if r.f == nil {
+		return nil
+	}
+
+	gzErr := r.gz.Close()
+	err := r.f.Close()
+	r.f, r.gz = nil, nil
+	if gzErr != nil {
+		return gzErr
+	}
+	return err
+}
+
+// Close stops recording, writes the ticks and fills already received from
+// the hub and closes the current file. Calling it again has no effect and
+// returns the same error.
+func (r *Recorder) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closeErr = r.closeFile()
+	})
+	return r.closeErr
+}
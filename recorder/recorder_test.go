@@ -0,0 +1,136 @@
+package recorder
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+func readAll(t *testing.T, files []string) []*Record {
+	var recs []*Record
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("error opening %s: %v", name, err)
+		}
+
+		r, err := NewReader(f)
+		if err != nil {
+			t.Fatalf("error reading %s: %v", name, err)
+		}
+
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("error decoding %s: %v", name, err)
+			}
+			recs = append(recs, rec)
+		}
+		r.Close()
+		f.Close()
+	}
+	return recs
+}
+
+func TestWriteAndRotate(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := New(Config{Dir: dir, Prefix: "TESTEX-FOOBAR", MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("error creating recorder: %v", err)
+	}
+
+	const n = 20
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+		tick.Quote.Bid = i
+		tick.Quote.QuoteTime = start.Add(time.Duration(i) * time.Second)
+		if err := r.Write(&Record{Type: TypeTick, Received: time.Now(), Tick: tick}); err != nil {
+			t.Fatalf("error writing record: %v", err)
+		}
+	}
+
+	fill := &sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Filled: 7}
+	if err := r.Write(&Record{Type: TypeFill, Received: time.Now(), Fill: fill}); err != nil {
+		t.Fatalf("error writing record: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("error closing recorder: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("error closing recorder twice: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "TESTEX-FOOBAR-*.ndjson.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	if len(files) < 2 {
+		t.Fatalf("expected the recording to rotate, got %d files", len(files))
+	}
+
+	recs := readAll(t, files)
+	if len(recs) != n+1 {
+		t.Fatalf("expected %d records, got %d", n+1, len(recs))
+	}
+
+	for i := 0; i < n; i++ {
+		if recs[i].Type != TypeTick || recs[i].Tick.Quote.Bid != i {
+			t.Errorf("record %d out of order: %+v", i, recs[i])
+		}
+		if !recs[i].Time().Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("record %d has wrong time %v", i, recs[i].Time())
+		}
+	}
+
+	if last := recs[n]; last.Type != TypeFill || last.Fill.Filled != 7 {
+		t.Errorf("unexpected final record: %+v", last)
+	}
+}
+
+func TestCloseDrains(t *testing.T) {
+	dir := t.TempDir()
+
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	r, err := New(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("error creating recorder: %v", err)
+	}
+
+	// Messages the hub has sent but the recorder has yet to write are
+	// still recorded when it closes
+	const n = 500
+	for i := 0; i < n; i++ {
+		r.tickch <- &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+		r.fillch <- &sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	}
+	r.Register(hub)
+	if err := r.Close(); err != nil {
+		t.Fatalf("error closing recorder: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "TESTEX-FOOBAR-*.ndjson.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs := readAll(t, files); len(recs) != 2*n {
+		t.Errorf("expected %d records, got %d", 2*n, len(recs))
+	}
+}
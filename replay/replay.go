@@ -0,0 +1,284 @@
+// Package replay plays back recordings made by the recorder package through
+// listeners that behave like the live sfclient TickListener and
+// FillListener.
+package replay
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/recorder"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+const (
+	// AsFastAsPossible plays records back without any delay between them
+	AsFastAsPossible = 0
+
+	// RealTime plays records back at the speed they were recorded
+	RealTime = 1
+)
+
+// Source is a recording to be played back. Ticks and fills are delivered
+// through independent listeners, just as they are by the exchange, but share
+// a clock so they stay in step with each other.
+type Source struct {
+	files  []string
+	speed  float64
+	logger *slog.Logger
+
+	mu      *sync.Mutex
+	seek    time.Time
+	started bool
+
+	startOnce *sync.Once
+	origin    time.Time
+	wallStart time.Time
+	startErr  error
+}
+
+// New creates a source playing files, which must be given in recording
+// order, at speed times the recorded rate. A speed of AsFastAsPossible
+// disables pacing.
+func New(files []string, speed float64) *Source {
+	return &Source{files: files, speed: speed, logger: slog.Default(), mu: &sync.Mutex{}, startOnce: &sync.Once{}}
+}
+
+// SetLogger sets the logger problems reading the recording are reported
+// to, slog.Default() if not set.
+func (s *Source) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// Glob returns the recording files in dir with the given prefix, in the
+// order they were recorded.
+func Glob(dir, prefix string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, prefix+"-*.ndjson.gz"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Seek skips every record timestamped before t. Playback starts when the
+// first listener does, after which Seek returns an error.
+func (s *Source) Seek(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("replay: cannot seek once playback has started")
+	}
+	s.seek = t
+	return nil
+}
+
+// start fixes the recorded and wall clock times that playback is paced from.
+func (s *Source) start() error {
+	s.startOnce.Do(func() {
+		s.mu.Lock()
+		s.started = true
+		s.mu.Unlock()
+
+		s.wallStart = time.Now()
+		if !s.seek.IsZero() {
+			s.origin = s.seek
+			return
+		}
+
+		err := s.each(func(rec *recorder.Record) bool {
+			s.origin = rec.Time()
+			return false
+		})
+		if err != nil {
+			s.startErr = err
+		} else if s.origin.IsZero() {
+			s.startErr = errors.New("recording is empty")
+		}
+	})
+	return s.startErr
+}
+
+// each calls fn with every record at or after the seek time until fn returns
+// false.
+func (s *Source) each(fn func(rec *recorder.Record) bool) error {
+	for _, name := range s.files {
+		cont, err := s.eachInFile(name, fn)
+		if err != nil || !cont {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Source) eachInFile(name string, fn func(rec *recorder.Record) bool) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r, err := recorder.NewReader(f)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return true, nil
+		} else if err == io.ErrUnexpectedEOF {
+			// The recorder was killed mid-write, keep what we have
+			s.logger.Warn("replay: recording is truncated", "file", name)
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		if rec.Time().Before(s.seek) {
+			continue
+		}
+		if !fn(rec) {
+			return false, nil
+		}
+	}
+}
+
+// wait blocks until rec is due to be played, returning false if closed
+// first.
+func (s *Source) wait(rec *recorder.Record, closed <-chan struct{}) bool {
+	if s.speed <= AsFastAsPossible {
+		select {
+		case <-closed:
+			return false
+		default:
+			return true
+		}
+	}
+
+	offset := time.Duration(float64(rec.Time().Sub(s.origin)) / s.speed)
+	d := time.Until(s.wallStart.Add(offset))
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-closed:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// play passes records to send until the recording ends or send returns
+// false.
+func (s *Source) play(send func(rec *recorder.Record) bool) {
+	if err := s.each(send); err != nil {
+		s.logger.Error("replay: error reading recording", "err", err)
+	}
+}
+
+type TickListener struct {
+	src       *Source
+	close     chan struct{}
+	closeOnce *sync.Once
+	messages  chan *sfclient.TickMessage
+}
+
+// TickListener returns a listener for the recorded ticks.
+func (s *Source) TickListener() *TickListener {
+	return &TickListener{
+		src:       s,
+		close:     make(chan struct{}),
+		closeOnce: &sync.Once{},
+		messages:  make(chan *sfclient.TickMessage, 100),
+	}
+}
+
+func (t *TickListener) Listen() (<-chan *sfclient.TickMessage, error) {
+	if err := t.src.start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer close(t.messages)
+		t.src.play(func(rec *recorder.Record) bool {
+			if rec.Tick == nil {
+				return true
+			}
+			if !t.src.wait(rec, t.close) {
+				return false
+			}
+			select {
+			case t.messages <- rec.Tick:
+				return true
+			case <-t.close:
+				return false
+			}
+		})
+	}()
+
+	return t.messages, nil
+}
+
+// Close stops playback to the listener. It may be called more than once.
+func (t *TickListener) Close() {
+	t.closeOnce.Do(func() { close(t.close) })
+}
+
+type FillListener struct {
+	src       *Source
+	close     chan struct{}
+	closeOnce *sync.Once
+	messages  chan *sfclient.FillMessage
+}
+
+// FillListener returns a listener for the recorded fills.
+func (s *Source) FillListener() *FillListener {
+	return &FillListener{
+		src:       s,
+		close:     make(chan struct{}),
+		closeOnce: &sync.Once{},
+		messages:  make(chan *sfclient.FillMessage, 100),
+	}
+}
+
+func (f *FillListener) Listen() (<-chan *sfclient.FillMessage, error) {
+	if err := f.src.start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer close(f.messages)
+		f.src.play(func(rec *recorder.Record) bool {
+			if rec.Fill == nil {
+				return true
+			}
+			if !f.src.wait(rec, f.close) {
+				return false
+			}
+			select {
+			case f.messages <- rec.Fill:
+				return true
+			case <-f.close:
+				return false
+			}
+		})
+	}()
+
+	return f.messages, nil
+}
+
+// Close stops playback to the listener. It may be called more than once.
+func (f *FillListener) Close() {
+	f.closeOnce.Do(func() { close(f.close) })
+}
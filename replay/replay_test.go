@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/recorder"
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+var epoch = time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+// record writes n ticks a second apart, with a fill after every other tick.
+func record(t *testing.T, n int) []string {
+	dir := t.TempDir()
+	r, err := recorder.New(recorder.Config{Dir: dir, Prefix: "TESTEX-FOOBAR"})
+	if err != nil {
+		t.Fatalf("error creating recorder: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		at := epoch.Add(time.Duration(i) * time.Second)
+		tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+		tick.Quote.Bid, tick.Quote.QuoteTime = i, at
+		if err := r.Write(&recorder.Record{Type: recorder.TypeTick, Received: at, Tick: tick}); err != nil {
+			t.Fatalf("error writing tick: %v", err)
+		}
+
+		if i%2 == 0 {
+			fill := &sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Filled: i, FilledAt: at}
+			if err := r.Write(&recorder.Record{Type: recorder.TypeFill, Received: at, Fill: fill}); err != nil {
+				t.Fatalf("error writing fill: %v", err)
+			}
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("error closing recorder: %v", err)
+	}
+
+	files, err := Glob(dir, "TESTEX-FOOBAR")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recording written: %v", err)
+	}
+	return files
+}
+
+func TestAsFastAsPossible(t *testing.T) {
+	src := New(record(t, 10), AsFastAsPossible)
+
+	ticks, err := src.TickListener().Listen()
+	if err != nil {
+		t.Fatalf("error listening to ticks: %v", err)
+	}
+	fills, err := src.FillListener().Listen()
+	if err != nil {
+		t.Fatalf("error listening to fills: %v", err)
+	}
+
+	var i int
+	for msg := range ticks {
+		if msg.Quote.Bid != i {
+			t.Errorf("expected tick %d, got %d", i, msg.Quote.Bid)
+		}
+		i++
+	}
+	if i != 10 {
+		t.Errorf("expected 10 ticks, got %d", i)
+	}
+
+	var n int
+	for msg := range fills {
+		if msg.Filled != 2*n {
+			t.Errorf("expected fill of %d, got %d", 2*n, msg.Filled)
+		}
+		n++
+	}
+	if n != 5 {
+		t.Errorf("expected 5 fills, got %d", n)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	src := New(record(t, 10), AsFastAsPossible)
+	if err := src.Seek(epoch.Add(7 * time.Second)); err != nil {
+		t.Fatalf("error seeking: %v", err)
+	}
+
+	ticks, err := src.TickListener().Listen()
+	if err != nil {
+		t.Fatalf("error listening to ticks: %v", err)
+	}
+
+	var got []int
+	for msg := range ticks {
+		got = append(got, msg.Quote.Bid)
+	}
+	if len(got) != 3 || got[0] != 7 {
+		t.Errorf("expected ticks 7 to 9, got %v", got)
+	}
+
+	if err := src.Seek(epoch); err == nil {
+		t.Error("expected an error seeking after playback started")
+	}
+}
+
+func TestPacing(t *testing.T) {
+	// 4 seconds of recording at 100x should take about 40ms
+	src := New(record(t, 5), 100)
+
+	start := time.Now()
+	ticks, err := src.TickListener().Listen()
+	if err != nil {
+		t.Fatalf("error listening to ticks: %v", err)
+	}
+	for range ticks {
+	}
+
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond || elapsed > time.Second {
+		t.Errorf("playback at 100x took %v", elapsed)
+	}
+}
+
+func TestClose(t *testing.T) {
+	src := New(record(t, 5), RealTime)
+
+	l := src.TickListener()
+	ticks, err := l.Listen()
+	if err != nil {
+		t.Fatalf("error listening to ticks: %v", err)
+	}
+
+	<-ticks
+	l.Close()
+	l.Close()
+
+	select {
+	case _, ok := <-ticks:
+		if ok {
+			// The next tick is a second away, so should never arrive
+			t.Error("received tick after close")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("listener did not stop after close")
+	}
+}
@@ -0,0 +1,211 @@
+package sfclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LocalBook is a StockHub component maintaining an estimate of the full
+// order book. It is seeded from StockOrderBook, kept up to date from the
+// top of book and depth carried by every tick, and periodically resynced
+// because ticks cannot describe changes below the best price.
+type LocalBook struct {
+	resync time.Duration
+	ch     chan *TickMessage
+
+	mu       *sync.RWMutex
+	bids     []AskBid // best (highest) first
+	asks     []AskBid // best (lowest) first
+	seededAt time.Time
+	updated  time.Time
+}
+
+// NewLocalBook creates a book that resyncs from a full snapshot every
+// resync. A resync of 0 only seeds the book once.
+func NewLocalBook(resync time.Duration) *LocalBook {
+	return &LocalBook{
+		resync: resync,
+		ch:     make(chan *TickMessage, 100),
+		mu:     &sync.RWMutex{},
+	}
+}
+
+func (b *LocalBook) Register(hub *StockHub) {
+	b.sync(hub)
+	hub.RegisterToTick(b.ch)
+	go b.init(hub)
+}
+
+func (b *LocalBook) init(hub *StockHub) {
+	var resync <-chan time.Time
+	if b.resync > 0 {
+		t := time.NewTicker(b.resync)
+		defer t.Stop()
+		resync = t.C
+	}
+
+	for {
+		select {
+		case msg := <-b.ch:
+			if msg.OK {
+				b.Apply(msg.Quote)
+			}
+		case <-resync:
+			b.sync(hub)
+		}
+	}
+}
+
+func (b *LocalBook) sync(hub *StockHub) {
+	book, err := hub.OrderBook()
+	if err != nil {
+		hub.Logger().Warn("error syncing local book", "err", err)
+		return
+	}
+	b.Seed(book)
+}
+
+// Seed replaces the book with a full snapshot.
+func (b *LocalBook) Seed(book *StockOrderBookResponse) {
+	bids := append([]AskBid(nil), book.Bids...)
+	asks := append([]AskBid(nil), book.Asks...)
+	sort.Slice(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	sort.Slice(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+
+	b.mu.Lock()
+	b.bids, b.asks = bids, asks
+	b.seededAt, b.updated = book.Timestamp, book.Timestamp
+	b.mu.Unlock()
+}
+
+// Apply updates the book from a quote. Quotes older than the last snapshot
+// are ignored.
+func (b *LocalBook) Apply(q StockState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q.QuoteTime.Before(b.seededAt) {
+		return
+	}
+
+	b.bids = applySide(b.bids, true, q.Bid, q.BidSize, q.BidDepth)
+	b.asks = applySide(b.asks, false, q.Ask, q.AskSize, q.AskDepth)
+	if q.QuoteTime.After(b.updated) {
+		b.updated = q.QuoteTime
+	}
+}
+
+// applySide sets the best level of a side to price and size, dropping any
+// levels better than it, then trims the worst levels until the side is no
+// deeper than depth. Liquidity added below the best price cannot be seen
+// until the next resync.
+func applySide(levels []AskBid, isBuy bool, price, size, depth int) []AskBid {
+	if price == 0 || size == 0 {
+		if depth == 0 {
+			return levels[:0]
+		}
+		// The best level emptied but we do not know the new best price,
+		// so only trim to the reported depth below.
+	} else {
+		i := 0
+		for i < len(levels) && ((isBuy && levels[i].Price > price) || (!isBuy && levels[i].Price < price)) {
+			i++
+		}
+		levels = levels[i:]
+
+		if len(levels) > 0 && levels[0].Price == price {
+			levels[0].Quantity = size
+		} else {
+			levels = append([]AskBid{{Price: price, Quantity: size, IsBuy: isBuy}}, levels...)
+		}
+	}
+
+	total := 0
+	for i, l := range levels {
+		if total+l.Quantity > depth {
+			if remaining := depth - total; remaining > 0 {
+				levels[i].Quantity = remaining
+				return levels[:i+1]
+			}
+			return levels[:i]
+		}
+		total += l.Quantity
+	}
+	return levels
+}
+
+// Depth returns up to n levels of each side of the book, best first. A
+// negative n returns no levels.
+func (b *LocalBook) Depth(n int) (bids, asks []AskBid) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	n = max(0, n)
+	bids = append([]AskBid(nil), b.bids[:min(n, len(b.bids))]...)
+	asks = append([]AskBid(nil), b.asks[:min(n, len(b.asks))]...)
+	return bids, asks
+}
+
+// Updated returns the exchange time of the latest snapshot or quote
+// applied to the book.
+func (b *LocalBook) Updated() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.updated
+}
+
+// PriceForQuantity returns the worst price and the total cost of
+// immediately buying (or selling if isBuy is false) qty shares. ok is false
+// if the book is not deep enough.
+func (b *LocalBook) PriceForQuantity(isBuy bool, qty int) (worst, cost int, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.priceForQuantity(isBuy, qty)
+}
+
+// priceForQuantity is PriceForQuantity for callers holding b.mu.
+func (b *LocalBook) priceForQuantity(isBuy bool, qty int) (worst, cost int, ok bool) {
+	levels := b.asks
+	if !isBuy {
+		levels = b.bids
+	}
+
+	remaining := qty
+	for _, l := range levels {
+		if remaining == 0 {
+			break
+		}
+		take := min(remaining, l.Quantity)
+		cost += take * l.Price
+		worst = l.Price
+		remaining -= take
+	}
+	return worst, cost, remaining == 0 && qty > 0
+}
+
+// Impact estimates the cost of immediately trading qty shares, in basis
+// points of the mid price: how much worse the average execution price is
+// than the mid. ok is false if there is no two sided market or the book is
+// not deep enough.
+func (b *LocalBook) Impact(isBuy bool, qty int) (bps float64, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return 0, false
+	}
+	mid := float64(b.bids[0].Price+b.asks[0].Price) / 2
+
+	// The mid and the cost come from the same book
+	_, cost, ok := b.priceForQuantity(isBuy, qty)
+	if !ok {
+		return 0, false
+	}
+
+	avg := float64(cost) / float64(qty)
+	if isBuy {
+		return 10000 * (avg - mid) / mid, true
+	}
+	return 10000 * (mid - avg) / mid, true
+}
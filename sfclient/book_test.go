@@ -0,0 +1,97 @@
+package sfclient
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var bookTime = time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+func seededBook() *LocalBook {
+	b := NewLocalBook(0)
+	b.Seed(&StockOrderBookResponse{
+		Bids:      []AskBid{{Price: 98, Quantity: 20, IsBuy: true}, {Price: 99, Quantity: 10, IsBuy: true}},
+		Asks:      []AskBid{{Price: 101, Quantity: 10}, {Price: 102, Quantity: 20}, {Price: 105, Quantity: 50}},
+		Timestamp: bookTime,
+	})
+	return b
+}
+
+func TestLocalBookSeed(t *testing.T) {
+	bids, asks := seededBook().Depth(2)
+
+	if len(bids) != 2 || bids[0].Price != 99 || bids[1].Price != 98 {
+		t.Errorf("bids not sorted best first: %+v", bids)
+	}
+	if len(asks) != 2 || asks[0].Price != 101 || asks[1].Price != 102 {
+		t.Errorf("asks not limited to 2 levels best first: %+v", asks)
+	}
+
+	if bids, asks := seededBook().Depth(-1); len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("expected no levels for a negative depth, got %+v %+v", bids, asks)
+	}
+}
+
+func TestLocalBookApply(t *testing.T) {
+	b := seededBook()
+
+	// The 101 ask is taken out and 5 shares taken from 102, while a new
+	// best bid appears at 100.
+	b.Apply(StockState{
+		Bid: 100, BidSize: 7, BidDepth: 37,
+		Ask: 102, AskSize: 15, AskDepth: 65,
+		QuoteTime: bookTime.Add(time.Second),
+	})
+
+	bids, asks := b.Depth(10)
+	if len(bids) != 3 || bids[0] != (AskBid{Price: 100, Quantity: 7, IsBuy: true}) {
+		t.Errorf("unexpected bids: %+v", bids)
+	}
+	if len(asks) != 2 || asks[0].Price != 102 || asks[0].Quantity != 15 || asks[1].Quantity != 50 {
+		t.Errorf("unexpected asks: %+v", asks)
+	}
+
+	// Depth shrinking means liquidity has left the back of the book
+	b.Apply(StockState{
+		Bid: 100, BidSize: 7, BidDepth: 20,
+		Ask: 102, AskSize: 15, AskDepth: 65,
+		QuoteTime: bookTime.Add(2 * time.Second),
+	})
+	bids, _ = b.Depth(10)
+	if len(bids) != 3 || bids[2].Quantity != 3 {
+		t.Errorf("expected bids trimmed to a depth of 20, got %+v", bids)
+	}
+
+	// Stale quotes from before the snapshot are ignored
+	b.Apply(StockState{QuoteTime: bookTime.Add(-time.Second)})
+	if bids, _ := b.Depth(1); len(bids) != 1 {
+		t.Error("stale quote was applied")
+	}
+
+	// An empty side clears it
+	b.Apply(StockState{Ask: 102, AskSize: 15, AskDepth: 65, QuoteTime: bookTime.Add(3 * time.Second)})
+	if bids, _ := b.Depth(10); len(bids) != 0 {
+		t.Errorf("expected no bids, got %+v", bids)
+	}
+}
+
+func TestLocalBookPriceForQuantity(t *testing.T) {
+	b := seededBook()
+
+	worst, cost, ok := b.PriceForQuantity(true, 25)
+	if !ok || worst != 102 || cost != 10*101+15*102 {
+		t.Errorf("unexpected price for 25: worst=%d cost=%d ok=%v", worst, cost, ok)
+	}
+
+	if _, _, ok := b.PriceForQuantity(false, 31); ok {
+		t.Error("expected not enough bids for 31 shares")
+	}
+
+	bps, ok := b.Impact(false, 30)
+	// Selling 30 averages (10*99+20*98)/30 against a mid of 100
+	want := 10000 * (100 - float64(10*99+20*98)/30) / 100
+	if !ok || math.Abs(bps-want) > 1e-9 {
+		t.Errorf("expected impact %.2fbps, got %.2f", want, bps)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"log/slog"
+	"net"
 	"net/http"
 	"path"
 	"strconv"
@@ -43,19 +45,39 @@ func (s *starTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	return s.RoundTripper.RoundTrip(r)
 }
 
-func New(apiKey string) *Client {
-	return &Client{
+func New(apiKey string, opts ...Option) *Client {
+	c := &Client{
 		baseURL:   "https://api.stockfighter.io/ob/api/",
 		baseWSURL: "wss://api.stockfighter.io/ob/api/ws/",
 		client: &http.Client{
 			Transport: &starTransport{apiKey: apiKey, RoundTripper: http.DefaultTransport},
-		}}
+		},
+		instruments: NewInstruments(),
+		logger:      slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 type Client struct {
-	baseURL   string
-	baseWSURL string
-	client    *http.Client
+	baseURL     string
+	baseWSURL   string
+	client      *http.Client
+	instruments *Instruments
+	logger      *slog.Logger
+}
+
+// Logger returns the logger the client and its listeners log through.
+func (c *Client) Logger() *slog.Logger {
+	return c.logger
+}
+
+// Instruments returns the latencies recorded by the client and its
+// listeners.
+func (c *Client) Instruments() *Instruments {
+	return c.instruments
 }
 
 func unmarshalResp(body io.Reader, reply maybeErr) error {
@@ -69,7 +91,12 @@ func unmarshalResp(body io.Reader, reply maybeErr) error {
 	return nil
 }
 
-func (c *Client) get(endpoint string, reply maybeErr) error {
+// get, postJSON and del call endpoint, recording the latency and any error
+// under name.
+func (c *Client) get(name, endpoint string, reply maybeErr) (err error) {
+	start := time.Now()
+	defer func() { c.instruments.request(name, start, coalesceErr(err, reply)) }()
+
 	resp, err := c.client.Get(c.baseURL + endpoint)
 	if err != nil {
 		return err
@@ -81,11 +108,14 @@ func (c *Client) get(endpoint string, reply maybeErr) error {
 	return coalesceErr( err, reply)
 }
 
-func (c *Client) postJSON(endpoint string, payload interface{}, reply maybeErr) error {
+func (c *Client) postJSON(name, endpoint string, payload interface{}, reply maybeErr) (err error) {
 	b, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
+
+	start := time.Now()
+	defer func() { c.instruments.request(name, start, coalesceErr(err, reply)) }()
 	resp, err := c.client.Post(c.baseURL+endpoint, "application/json", bytes.NewReader(b))
 	if err != nil {
 		return err
@@ -95,12 +125,14 @@ func (c *Client) postJSON(endpoint string, payload interface{}, reply maybeErr)
 	return unmarshalResp(resp.Body, reply)
 }
 
-func (c *Client) del(endpoint string, reply maybeErr) error {
+func (c *Client) del(name, endpoint string, reply maybeErr) (err error) {
 	req, err := http.NewRequest("DELETE", c.baseURL+endpoint, nil)
 	if err != nil {
 		return err
 	}
 
+	start := time.Now()
+	defer func() { c.instruments.request(name, start, coalesceErr(err, reply)) }()
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return err
@@ -132,19 +164,56 @@ func (a *APIResponse) Err() error {
 	}
 
 	if !a.OK {
-		return errors.New(a.Error)
+		return &APIError{Message: a.Error}
 	}
 
 	return nil
 }
 
+// APIError is returned when the exchange rejects a request.
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Classes of error counted by Instruments.
+const (
+	// ErrClassTransport is a failure to reach the exchange
+	ErrClassTransport = "transport"
+	// ErrClassDecode is a reply that could not be understood
+	ErrClassDecode = "decode"
+	// ErrClassAPI is a request rejected by the exchange
+	ErrClassAPI = "api"
+	// ErrClassOther is any error not in another class
+	ErrClassOther = "other"
+)
+
+func errorClass(err error) string {
+	var apiErr *APIError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &apiErr):
+		return ErrClassAPI
+	case errors.As(err, &netErr):
+		return ErrClassTransport
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrClassDecode
+	}
+	return ErrClassOther
+}
+
 type HeartbeatResponse struct {
 	APIResponse
 }
 
 func (c *Client) Heartbeat() (*HeartbeatResponse, error) {
 	hr := &HeartbeatResponse{}
-	err := c.get("heartbeat", hr)
+	err := c.get("heartbeat", "heartbeat", hr)
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +228,7 @@ type VenueHeartbeatResponse struct {
 
 func (c *Client) VenueHeartbeat(v Venue) (*VenueHeartbeatResponse, error) {
 	vhr := &VenueHeartbeatResponse{}
-	err := c.get(path.Join("venues", v.String(), "heartbeat"), vhr)
+	err := c.get("venue_heartbeat", path.Join("venues", v.String(), "heartbeat"), vhr)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +246,7 @@ type VenueStocksResponse struct {
 
 func (c *Client) VenueStocks(v Venue) (*VenueStocksResponse, error) {
 	vsr := &VenueStocksResponse{}
-	err := c.get(path.Join("venues", v.String(), "stocks"), vsr)
+	err := c.get("stocks", path.Join("venues", v.String(), "stocks"), vsr)
 
 	if err != nil {
 		return nil, err
@@ -203,7 +272,7 @@ type StockOrderBookResponse struct {
 
 func (c *Client) StockOrderBook(v Venue, s Symbol) (*StockOrderBookResponse, error) {
 	sor := &StockOrderBookResponse{}
-	err := c.get(path.Join("venues", v.String(), "stocks", s.String()), sor)
+	err := c.get("orderbook", path.Join("venues", v.String(), "stocks", s.String()), sor)
 	if err != nil {
 		return nil, err
 	}
@@ -250,11 +319,13 @@ type OrderResponse struct {
 
 func (c *Client) postOrder(req *orderRequest) (*OrderResponse, error) {
 	or := &OrderResponse{}
-	err := c.postJSON(path.Join("venues", req.Venue.String(), "stocks", req.Stock.String(), "orders"), req, or)
+	sent := time.Now()
+	err := c.postJSON("order", path.Join("venues", req.Venue.String(), "stocks", req.Stock.String(), "orders"), req, or)
 	if err != nil {
 		return nil, err
 	}
 
+	c.instruments.ordered(or, sent)
 	return or, nil
 }
 
@@ -340,7 +411,7 @@ type QuoteResponse struct {
 
 func (c *Client) Quote(venue Venue, stock Symbol) (*QuoteResponse, error) {
 	qr := &QuoteResponse{}
-	err := c.get(path.Join("venues", venue.String(), "stocks", stock.String(), "quote"), qr)
+	err := c.get("quote", path.Join("venues", venue.String(), "stocks", stock.String(), "quote"), qr)
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +444,7 @@ type StatusResponse struct {
 
 func (c *Client) OrderStatus(venue Venue, stock Symbol, id int) (*StatusResponse, error) {
 	sr := &StatusResponse{}
-	err := c.get(path.Join("venues", venue.String(), "stocks", stock.String(), "orders", strconv.Itoa(id)), sr)
+	err := c.get("status", path.Join("venues", venue.String(), "stocks", stock.String(), "orders", strconv.Itoa(id)), sr)
 	if err != nil {
 		return nil, err
 	}
@@ -385,11 +456,12 @@ type CancelOrderResponse StatusResponse
 
 func (c *Client) CancelOrder(venue Venue, stock Symbol, id int) (*CancelOrderResponse, error) {
 	cor := &CancelOrderResponse{}
-	err := c.del(path.Join("venues", venue.String(), "stocks", stock.String(), "orders", strconv.Itoa(id)), cor)
+	err := c.del("cancel", path.Join("venues", venue.String(), "stocks", stock.String(), "orders", strconv.Itoa(id)), cor)
 	if err != nil {
 		return nil, err
 	}
 
+	c.instruments.cancelled(venue, id)
 	return cor, nil
 }
 
@@ -400,7 +472,7 @@ type MultiStatusResponse struct {
 
 func (c *Client) VenueOrdersStatus(account string, venue Venue) (*MultiStatusResponse, error) {
 	vr := &MultiStatusResponse{}
-	err := c.get(path.Join("venues", venue.String(), "accounts", account, "orders"), vr)
+	err := c.get("venue_orders", path.Join("venues", venue.String(), "accounts", account, "orders"), vr)
 	if err != nil {
 		return nil, err
 	}
@@ -410,7 +482,7 @@ func (c *Client) VenueOrdersStatus(account string, venue Venue) (*MultiStatusRes
 
 func (c *Client) StockOrdersStatus(account string, venue Venue, stock Symbol) (*MultiStatusResponse, error) {
 	mr := &MultiStatusResponse{}
-	err := c.get(path.Join("venues", venue.String(), "accounts", account, "stocks", stock.String(), "orders"), mr)
+	err := c.get("stock_orders", path.Join("venues", venue.String(), "accounts", account, "stocks", stock.String(), "orders"), mr)
 	if err != nil {
 		return nil, err
 	}
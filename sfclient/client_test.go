@@ -3,6 +3,7 @@ package sfclient
 import (
 	"flag"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 )
@@ -12,9 +13,10 @@ var (
 	c      *Client
 )
 
-func init() {
+func TestMain(m *testing.M) {
 	flag.Parse()
 	c = New(*apiKey)
+	os.Exit(m.Run())
 }
 
 func checkerr(resp APIResponse, err error) error {
@@ -0,0 +1,194 @@
+package sfclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type timeEntry struct {
+	t    time.Time
+	vals [numFields]int
+
+	// running totals up to and including this entry, so the sum over any
+	// range of entries is a subtraction
+	cum [numFields]int64
+	cnt [numFields]int
+}
+
+// TimeHistory is a StockHub component recording bid, ask, last, spread and
+// mid prices indexed by QuoteTime, so they can be queried over the last N
+// seconds regardless of how quickly ticks arrive. Windows are measured back
+// from the newest quote rather than the wall clock, so recorded data can be
+// queried in the same way as live data.
+type TimeHistory struct {
+	retain time.Duration
+	ch     chan *TickMessage
+
+	mu      *sync.RWMutex
+	entries []timeEntry
+	head    int
+
+	// totals of the entries evicted so far
+	baseCum [numFields]int64
+	baseCnt [numFields]int
+
+	// latest known prices, carried forward when a side of the book is empty
+	curr [numFields]int
+}
+
+// NewTimeHistory creates a history keeping quotes for retain. Queries over
+// longer periods only see what has been retained.
+func NewTimeHistory(retain time.Duration) *TimeHistory {
+	return &TimeHistory{
+		retain: retain,
+		ch:     make(chan *TickMessage, 100),
+		mu:     &sync.RWMutex{},
+	}
+}
+
+func (h *TimeHistory) Register(hub *StockHub) {
+	hub.RegisterToTick(h.ch)
+	go h.init()
+}
+
+func (h *TimeHistory) init() {
+	for msg := range h.ch {
+		if msg.OK {
+			h.Add(msg.Quote)
+		}
+	}
+}
+
+// Add records a quote. A quote older than the newest already recorded is
+// recorded at the newest time, keeping the index sorted.
+func (h *TimeHistory) Add(q StockState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Quote previous price, if currently no bid/asks
+	if q.Bid > 0 {
+		h.curr[FieldBid] = q.Bid
+	}
+	if q.Ask > 0 {
+		h.curr[FieldAsk] = q.Ask
+	}
+	if q.Last > 0 {
+		h.curr[FieldLast] = q.Last
+	}
+	if h.curr[FieldBid] > 0 && h.curr[FieldAsk] > 0 {
+		h.curr[FieldSpread] = h.curr[FieldAsk] - h.curr[FieldBid]
+		h.curr[FieldMid] = (h.curr[FieldAsk] + h.curr[FieldBid]) / 2
+	}
+
+	e := timeEntry{t: q.QuoteTime, vals: h.curr}
+	prevCum, prevCnt := h.baseCum, h.baseCnt
+	if n := len(h.entries); n > h.head {
+		last := h.entries[n-1]
+		prevCum, prevCnt = last.cum, last.cnt
+		if e.t.Before(last.t) {
+			e.t = last.t
+		}
+	}
+
+	for f := range e.vals {
+		e.cum[f], e.cnt[f] = prevCum[f], prevCnt[f]
+		if e.vals[f] > 0 {
+			e.cum[f] += int64(e.vals[f])
+			e.cnt[f]++
+		}
+	}
+	h.entries = append(h.entries, e)
+
+	h.evict(e.t.Add(-h.retain))
+}
+
+func (h *TimeHistory) evict(cutoff time.Time) {
+	for h.head < len(h.entries) && h.entries[h.head].t.Before(cutoff) {
+		h.baseCum, h.baseCnt = h.entries[h.head].cum, h.entries[h.head].cnt
+		h.head++
+	}
+
+	// Reclaim the evicted prefix once it dominates the backing array
+	if h.head > 1024 && h.head > len(h.entries)/2 {
+		h.entries = append(h.entries[:0], h.entries[h.head:]...)
+		h.head = 0
+	}
+}
+
+// window returns the index of the first entry within d of the newest, or
+// len(h.entries) if there are none. Must be called with h.mu held.
+func (h *TimeHistory) window(d time.Duration) int {
+	live := h.entries[h.head:]
+	if len(live) == 0 {
+		return len(h.entries)
+	}
+
+	cutoff := live[len(live)-1].t.Add(-d)
+	return h.head + sort.Search(len(live), func(i int) bool {
+		return !live[i].t.Before(cutoff)
+	})
+}
+
+// Avg returns the mean of f over quotes in the last d, in O(log n). ok is
+// false if there were none with a price for f.
+func (h *TimeHistory) Avg(f Field, d time.Duration) (avg float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	start := h.window(d)
+	if start == len(h.entries) {
+		return 0, false
+	}
+
+	cum, cnt := h.baseCum[f], h.baseCnt[f]
+	if start > h.head {
+		cum, cnt = h.entries[start-1].cum[f], h.entries[start-1].cnt[f]
+	}
+	last := h.entries[len(h.entries)-1]
+
+	n := last.cnt[f] - cnt
+	if n == 0 {
+		return 0, false
+	}
+	return float64(last.cum[f]-cum) / float64(n), true
+}
+
+// Quantile returns the exact q quantile of f over quotes in the last d. ok
+// is false if there were none with a price for f.
+func (h *TimeHistory) Quantile(f Field, d time.Duration, q float64) (v int, ok bool) {
+	vals := h.Values(f, d)
+	if len(vals) == 0 {
+		return 0, false
+	}
+
+	sort.Ints(vals)
+	i := int(q * float64(len(vals)))
+	if i >= len(vals) {
+		i = len(vals) - 1
+	} else if i < 0 {
+		i = 0
+	}
+	return vals[i], true
+}
+
+// Values returns the prices of f over quotes in the last d, oldest first.
+func (h *TimeHistory) Values(f Field, d time.Duration) []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var vals []int
+	for _, e := range h.entries[h.window(d):] {
+		if e.vals[f] > 0 {
+			vals = append(vals, e.vals[f])
+		}
+	}
+	return vals
+}
+
+// Count returns the number of quotes recorded in the last d.
+func (h *TimeHistory) Count(d time.Duration) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.entries) - h.window(d)
+}
@@ -0,0 +1,98 @@
+package sfclient
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTimeHistory(t *testing.T) {
+	h := NewTimeHistory(time.Minute)
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	type quote struct {
+		t   time.Time
+		ask int
+	}
+	var quotes []quote
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	now := start
+	for i := 0; i < 10000; i++ {
+		// Bursty arrivals so tick counts and durations disagree
+		now = now.Add(time.Duration(rng.Int63n(int64(50 * time.Millisecond))))
+		if i%1000 < 100 {
+			now = now.Add(time.Second)
+		}
+		ask := 1000 + int(rng.Int31n(100))
+		quotes = append(quotes, quote{now, ask})
+		h.Add(StockState{Ask: ask, Bid: ask - 10, QuoteTime: now})
+
+		if i%997 != 0 {
+			continue
+		}
+
+		for _, d := range []time.Duration{time.Second, 10 * time.Second, time.Minute} {
+			var window []int
+			sum := 0
+			for _, q := range quotes {
+				if !q.t.Before(now.Add(-d)) {
+					window = append(window, q.ask)
+					sum += q.ask
+				}
+			}
+
+			avg, ok := h.Avg(FieldAsk, d)
+			if want := float64(sum) / float64(len(window)); !ok || avg != want {
+				t.Fatalf("%d: expected average ask over %v of %f, got %f", i, d, want, avg)
+			}
+			if h.Count(d) != len(window) {
+				t.Fatalf("%d: expected %d quotes in %v, got %d", i, len(window), d, h.Count(d))
+			}
+
+			sort.Ints(window)
+			median, _ := h.Quantile(FieldAsk, d, 0.5)
+			if want := window[len(window)/2]; median != want {
+				t.Fatalf("%d: expected median ask over %v of %d, got %d", i, d, want, median)
+			}
+
+			spread, _ := h.Avg(FieldSpread, d)
+			if spread != 10 {
+				t.Fatalf("%d: expected spread of 10, got %f", i, spread)
+			}
+		}
+	}
+
+	// Nothing older than the retention period is kept
+	if n := len(h.entries) - h.head; n != h.Count(time.Hour) {
+		t.Errorf("retained %d entries but %d are queryable", n, h.Count(time.Hour))
+	}
+	if oldest := h.entries[h.head].t; now.Sub(oldest) > time.Minute {
+		t.Errorf("retained quote from %v, older than a minute", now.Sub(oldest))
+	}
+}
+
+func TestTimeHistoryCarriesForward(t *testing.T) {
+	h := NewTimeHistory(time.Minute)
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := h.Avg(FieldBid, time.Second); ok {
+		t.Error("expected no average from an empty history")
+	}
+
+	h.Add(StockState{Bid: 100, QuoteTime: start})
+	h.Add(StockState{Ask: 110, QuoteTime: start.Add(time.Second)})
+	// Out of order quotes are recorded at the newest time
+	h.Add(StockState{Bid: 104, Ask: 110, Last: 105, QuoteTime: start})
+
+	if bids := h.Values(FieldBid, time.Minute); len(bids) != 3 || bids[1] != 100 || bids[2] != 104 {
+		t.Errorf("unexpected bids: %v", bids)
+	}
+	if lasts := h.Values(FieldLast, time.Minute); len(lasts) != 1 {
+		t.Errorf("expected a single last price, got %v", lasts)
+	}
+	if avg, _ := h.Avg(FieldBid, 0); avg != 102 {
+		t.Errorf("expected the last two quotes at the same time to average 102, got %f", avg)
+	}
+}
@@ -1,39 +1,53 @@
 package sfclient
 
 import (
+	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 type StockHub struct {
 	stock   Symbol
 	venue   Venue
 	account string
-	tl      *TickListener
-	fl      *FillListener
+	tl      TickSource
+	fl      FillSource
 	ticker  <-chan *TickMessage
 	fills   <-chan *FillMessage
-	client  *Client
+	md      MarketData
+	og      OrderGateway
+	logger  *slog.Logger
 
 	tickMu        *sync.Mutex
 	tickListeners []chan *TickMessage
 
 	fillMu        *sync.Mutex
 	fillListeners []chan *FillMessage
+
+	// messages not delivered because a listener was not ready
+	droppedTicks atomic.Int64
+	droppedFills atomic.Int64
 }
 
 type Registerer interface {
 	Register(*StockHub)
 }
 
-func NewStockHub(c *Client, account string, venue Venue, stock Symbol) (*StockHub, error) {
-	ret := &StockHub{stock: stock, venue: venue, account: account, client: c, tickMu: &sync.Mutex{}, fillMu: &sync.Mutex{}}
+// NewStockHub creates a hub trading stock on venue for account. Quotes and
+// ticks come from md, while orders are routed to and fills reported by og. A
+// *Client can be used for both. The hub logs through the logger of md, if
+// it has one.
+func NewStockHub(md MarketData, og OrderGateway, account string, venue Venue, stock Symbol) (*StockHub, error) {
+	ret := &StockHub{stock: stock, venue: venue, account: account, md: md, og: og, tickMu: &sync.Mutex{}, fillMu: &sync.Mutex{}}
+	ret.logger = tradingLogger(LoggerOf(md), account, venue, stock)
 
-	tl, err := c.StockTicker(account, venue, stock)
+	tl, err := md.StockTickSource(account, venue, stock)
 	if err != nil {
 		return nil, err
 	}
 
-	fl, err := c.StockFills(account, venue, stock)
+	fl, err := og.StockFillSource(account, venue, stock)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +74,7 @@ func NewStockHub(c *Client, account string, venue Venue, stock Symbol) (*StockHu
 }
 
 func (h *StockHub) Buy(price, qty int, typ OrderType) (*OrderResponse, error) {
-	return h.client.BuyOrder(h.account, h.venue, h.stock, price, qty, typ)
+	return h.og.BuyOrder(h.account, h.venue, h.stock, price, qty, typ)
 }
 
 func (h *StockHub) BuyLimit(price int, qty int) (*OrderResponse, error) {
@@ -79,7 +93,7 @@ func (h *StockHub) BuyIOC(price int, qty int) (*OrderResponse, error) {
 }
 
 func (h *StockHub) Sell(price, qty int, typ OrderType) (*OrderResponse, error) {
-	return h.client.SellOrder(h.account, h.venue, h.stock, price, qty, typ)
+	return h.og.SellOrder(h.account, h.venue, h.stock, price, qty, typ)
 }
 
 func (h *StockHub) SellLimit(price, qty int) (*OrderResponse, error) {
@@ -99,6 +113,44 @@ func (h *StockHub) SellIOC(price, qty int) (*OrderResponse, error) {
 	return h.Sell(price, qty, TypeImmediateOrCancel)
 }
 
+func (h *StockHub) Cancel(id int) (*CancelOrderResponse, error) {
+	return h.og.CancelOrder(h.venue, h.stock, id)
+}
+
+func (h *StockHub) OrderBook() (*StockOrderBookResponse, error) {
+	return h.md.StockOrderBook(h.venue, h.stock)
+}
+
+func (h *StockHub) Quote() (*QuoteResponse, error) {
+	return h.md.Quote(h.venue, h.stock)
+}
+
+func (h *StockHub) OrderStatus(id int) (*StatusResponse, error) {
+	return h.og.OrderStatus(h.venue, h.stock, id)
+}
+
+func (h *StockHub) Orders() (*MultiStatusResponse, error) {
+	return h.og.StockOrdersStatus(h.account, h.venue, h.stock)
+}
+
+func (h *StockHub) Account() string {
+	return h.account
+}
+
+func (h *StockHub) Venue() Venue {
+	return h.venue
+}
+
+func (h *StockHub) Stock() Symbol {
+	return h.stock
+}
+
+// Logger returns a logger with fields for the hub's account, venue and
+// stock, for components registered with the hub to log through.
+func (h *StockHub) Logger() *slog.Logger {
+	return h.logger
+}
+
 func (h *StockHub) RegisterComponenets(cmpts ...Registerer) {
 	for _, cmpt := range cmpts {
 		cmpt.Register(h)
@@ -114,19 +166,46 @@ func (h *StockHub) RegisterToTick(recv chan *TickMessage) {
 func (h *StockHub) RegisterToFills(recv chan *FillMessage) {
 	h.fillMu.Lock()
 	h.fillListeners = append(h.fillListeners, recv)
+	h.fillMu.Unlock()
+}
+
+// UnregisterFromTick stops ticks being sent to recv.
+func (h *StockHub) UnregisterFromTick(recv chan *TickMessage) {
+	h.tickMu.Lock()
+	h.tickListeners = slices.DeleteFunc(h.tickListeners, func(ch chan *TickMessage) bool { return ch == recv })
+	h.tickMu.Unlock()
+}
+
+// UnregisterFromFills stops fills being sent to recv.
+func (h *StockHub) UnregisterFromFills(recv chan *FillMessage) {
 	h.fillMu.Lock()
+	h.fillListeners = slices.DeleteFunc(h.fillListeners, func(ch chan *FillMessage) bool { return ch == recv })
+	h.fillMu.Unlock()
+}
+
+// Dropped returns the number of ticks and fills not delivered to a
+// registered channel because it was full. Each listener missing a message
+// counts once.
+func (h *StockHub) Dropped() (ticks, fills int) {
+	return int(h.droppedTicks.Load()), int(h.droppedFills.Load())
+}
+
+// Close stops the tick and fill streams. Registered channels receive
+// nothing further.
+func (h *StockHub) Close() {
+	h.tl.Close()
+	h.fl.Close()
 }
 
-// No way to cancel at the moment lolol
 func (h *StockHub) startSendTicks() {
-	for {
-		msg := <-h.ticker
+	for msg := range h.ticker {
 		h.tickMu.Lock()
 		for _, ch := range h.tickListeners {
 			// Non-blocking send on channels
 			select {
 			case ch <- msg:
 			default:
+				h.droppedTicks.Add(1)
 			}
 		}
 		h.tickMu.Unlock()
@@ -134,14 +213,14 @@ func (h *StockHub) startSendTicks() {
 }
 
 func (h *StockHub) startSendFills() {
-	for {
-		msg := <-h.fills
+	for msg := range h.fills {
 		h.fillMu.Lock()
 		for _, ch := range h.fillListeners {
 			// Non-blocking send on channels
 			select {
 			case ch <- msg:
 			default:
+				h.droppedFills.Add(1)
 			}
 		}
 		h.fillMu.Unlock()
@@ -208,6 +287,10 @@ func (h *BidAskHistory) Current() (ask, askSize, bid, bidSize int) {
 	return curr.ask, curr.askSize, curr.bid, curr.bidSize
 }
 
+// Avg returns the mean ask and bid over the last x ticks, or zeros if no
+// ticks have been received.
+//
+// Deprecated: MarketStats keeps the same averages, and more, in O(1).
 func (h *BidAskHistory) Avg(x int) (ask, bid int) {
 	if x > h.n {
 		x = h.n
@@ -219,6 +302,10 @@ func (h *BidAskHistory) Avg(x int) (ask, bid int) {
 	if x > h.i {
 		x = h.i
 	}
+	if x <= 0 {
+		h.mu.Unlock()
+		return 0, 0
+	}
 	curr := h.i % h.n
 	for j := 0; j < x; j++ {
 		e := h.elems[((curr+h.n)-j)%h.n]
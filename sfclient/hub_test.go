@@ -0,0 +1,137 @@
+package sfclient_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+func TestStockHubFanOut(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	ticks1 := make(chan *sfclient.TickMessage, 10)
+	ticks2 := make(chan *sfclient.TickMessage, 10)
+	fills := make(chan *sfclient.FillMessage, 10)
+	hub.RegisterToTick(ticks1)
+	hub.RegisterToTick(ticks2)
+	hub.RegisterToFills(fills)
+
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid = 42
+	m.PushTick(tick)
+	m.PushFill(&sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Filled: 7})
+
+	for _, ch := range []chan *sfclient.TickMessage{ticks1, ticks2} {
+		select {
+		case msg := <-ch:
+			if msg.Quote.Bid != 42 {
+				t.Errorf("expected bid 42, got %d", msg.Quote.Bid)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("tick not delivered")
+		}
+	}
+
+	select {
+	case msg := <-fills:
+		if msg.Filled != 7 {
+			t.Errorf("expected 7 filled, got %d", msg.Filled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fill not delivered")
+	}
+}
+
+func TestStockHubOrders(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	br, err := hub.BuyLimit(100, 10)
+	if err != nil {
+		t.Fatalf("error buying: %v", err)
+	}
+	if _, err := hub.SellIOC(110, 5); err != nil {
+		t.Fatalf("error selling: %v", err)
+	}
+	if _, err := hub.Cancel(br.ID); err != nil {
+		t.Fatalf("error cancelling: %v", err)
+	}
+
+	orders := m.Orders()
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(orders))
+	}
+	if o := orders[0]; o.Direction != "buy" || o.Price != 100 || o.Account != "EXB123456" || o.Symbol != "FOOBAR" || o.Open {
+		t.Errorf("unexpected buy order: %+v", o)
+	}
+	if o := orders[1]; o.Direction != "sell" || o.OrderType != sfclient.TypeImmediateOrCancel || !o.Open {
+		t.Errorf("unexpected sell order: %+v", o)
+	}
+}
+
+func TestStockHubDropped(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	// Nobody receives on an unbuffered channel, so every message is dropped
+	hub.RegisterToTick(make(chan *sfclient.TickMessage))
+	hub.RegisterToFills(make(chan *sfclient.FillMessage))
+	m.PushTick(&sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}})
+	m.PushTick(&sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}})
+	m.PushFill(&sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		ticks, fills := hub.Dropped()
+		if ticks == 2 && fills == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 ticks and 1 fill dropped, got %d and %d", ticks, fills)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// loggedMarket is a market with its own logger, like a *sfclient.Client.
+type loggedMarket struct {
+	*sftest.Market
+	logger *slog.Logger
+}
+
+func (m loggedMarket) Logger() *slog.Logger {
+	return m.logger
+}
+
+func TestStockHubLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m := loggedMarket{sftest.NewMarket(), slog.New(slog.NewTextHandler(&buf, nil))}
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	hub.Logger().Info("hello")
+	if got := buf.String(); !strings.Contains(got, "account=EXB123456 venue=TESTEX symbol=FOOBAR") {
+		t.Errorf("expected hub fields, got %q", got)
+	}
+}
@@ -0,0 +1,349 @@
+package sfclient
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/quantest"
+)
+
+// LatencyQuantiles are the quantiles latencies are estimated at.
+var LatencyQuantiles = []quantest.Quantile{
+	quantest.NewQuantile(0.5, 0.01),
+	quantest.NewQuantile(0.9, 0.005),
+	quantest.NewQuantile(0.99, 0.001),
+}
+
+// pendingTTL is how long an order waits for its first fill before it is no
+// longer tracked.
+const pendingTTL = time.Hour
+
+// LatencySummary describes the latencies observed for one operation.
+type LatencySummary struct {
+	Count  int
+	Errors int
+	Sum    time.Duration
+	Mean   time.Duration
+	Max    time.Duration
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+
+	// ErrorClasses counts Errors by class, such as ErrClassTransport
+	ErrorClasses map[string]int
+}
+
+type latency struct {
+	est     *quantest.CKMS[time.Duration]
+	count   int
+	errors  map[string]int
+	nErrors int
+	sum     time.Duration
+	max     time.Duration
+}
+
+func newLatency() *latency {
+	return &latency{
+		est:    quantest.NewCKMS[time.Duration](LatencyQuantiles),
+		errors: map[string]int{},
+	}
+}
+
+func (l *latency) observe(d time.Duration) {
+	l.est.Insert(d)
+	l.count++
+	l.sum += d
+	l.max = max(l.max, d)
+}
+
+func (l *latency) failed(err error) {
+	l.errors[errorClass(err)]++
+	l.nErrors++
+}
+
+func (l *latency) summary() LatencySummary {
+	s := LatencySummary{Count: l.count, Errors: l.nErrors, Sum: l.sum, Max: l.max}
+	s.ErrorClasses = make(map[string]int, len(l.errors))
+	for class, n := range l.errors {
+		s.ErrorClasses[class] = n
+	}
+	if l.count > 0 {
+		s.Mean = l.sum / time.Duration(l.count)
+		s.P50, s.P90, s.P99 = l.est.Query(0.5), l.est.Query(0.9), l.est.Query(0.99)
+	}
+	return s
+}
+
+// Websocket streams whose reconnects are counted.
+const (
+	StreamTicks = "ticks"
+	StreamFills = "fills"
+)
+
+// Reconnects counts the attempts to reconnect a websocket stream.
+type Reconnects struct {
+	OK     int
+	Failed int
+}
+
+type orderKey struct {
+	venue Venue
+	id    int
+}
+
+// Instruments records how long the exchange takes to do things for us: the
+// latency of each API endpoint, the lag between the exchange timestamping a
+// quote or execution and it arriving over the websocket, and the time from
+// sending an order to receiving its first execution. It also counts
+// websocket reconnects. Every Client has one, shared by its listeners.
+type Instruments struct {
+	mu          *sync.Mutex
+	endpoints   map[string]*latency
+	tickLag     *latency
+	fillLag     *latency
+	orderToFill *latency
+	reconnects  map[string]*Reconnects
+
+	// pending holds when each order awaiting its first execution was
+	// sent, and early when executions arrived for orders whose responses
+	// have not yet been seen
+	pending *waitQueue
+	early   *waitQueue
+}
+
+func NewInstruments() *Instruments {
+	return &Instruments{
+		mu:          &sync.Mutex{},
+		endpoints:   map[string]*latency{},
+		tickLag:     newLatency(),
+		fillLag:     newLatency(),
+		orderToFill: newLatency(),
+		reconnects:  map[string]*Reconnects{},
+		pending:     newWaitQueue(),
+		early:       newWaitQueue(),
+	}
+}
+
+// request records a call to endpoint that started at start.
+func (in *Instruments) request(endpoint string, start time.Time, err error) {
+	d := time.Since(start)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	l, ok := in.endpoints[endpoint]
+	if !ok {
+		l = newLatency()
+		in.endpoints[endpoint] = l
+	}
+	l.observe(d)
+	if err != nil {
+		l.failed(err)
+	}
+}
+
+// reconnect records an attempt to reconnect a websocket stream, such as
+// StreamTicks, that failed if err is not nil.
+func (in *Instruments) reconnect(stream string, err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	r, ok := in.reconnects[stream]
+	if !ok {
+		r = &Reconnects{}
+		in.reconnects[stream] = r
+	}
+	if err != nil {
+		r.Failed++
+	} else {
+		r.OK++
+	}
+}
+
+// tick records the arrival of a quote.
+func (in *Instruments) tick(q StockState, received time.Time) {
+	if q.QuoteTime.IsZero() {
+		return
+	}
+
+	in.mu.Lock()
+	in.tickLag.observe(received.Sub(q.QuoteTime))
+	in.mu.Unlock()
+}
+
+// ordered records that the order resp was sent at sent.
+func (in *Instruments) ordered(resp *OrderResponse, sent time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	key := orderKey{resp.Venue, resp.ID}
+	if received, ok := in.early.remove(key); ok {
+		in.orderToFill.observe(received.Sub(sent))
+		return
+	}
+	// Killed orders will never be filled
+	if resp.Open || resp.TotalFilled > 0 {
+		in.pending.add(key, sent)
+	}
+	in.expire(sent)
+}
+
+// cancelled stops waiting for the first execution of an order.
+func (in *Instruments) cancelled(venue Venue, id int) {
+	in.mu.Lock()
+	in.pending.remove(orderKey{venue, id})
+	in.mu.Unlock()
+}
+
+// fill records the arrival of an execution.
+func (in *Instruments) fill(msg *FillMessage, received time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if !msg.FilledAt.IsZero() {
+		in.fillLag.observe(received.Sub(msg.FilledAt))
+	}
+
+	key := orderKey{msg.Venue, msg.Order.ID}
+	if sent, ok := in.pending.remove(key); ok {
+		in.orderToFill.observe(received.Sub(sent))
+	} else if msg.Order.TotalFilled == msg.Filled {
+		// The first execution, arriving before the order response
+		in.early.add(key, received)
+	}
+}
+
+// expire forgets orders that have waited too long. Must be called with
+// in.mu held.
+func (in *Instruments) expire(now time.Time) {
+	in.pending.expire(now.Add(-pendingTTL))
+	in.early.expire(now.Add(-pendingTTL))
+}
+
+// waitQueue holds when each of a set of orders started waiting, and the
+// orders in that time order, so that expiring the oldest stops at the first
+// that has not waited too long. Orders removed from the set stay queued
+// until they reach the front, or the queue is compacted.
+type waitQueue struct {
+	since map[orderKey]time.Time
+	queue []waiting // oldest first
+}
+
+type waiting struct {
+	key orderKey
+	at  time.Time
+}
+
+func newWaitQueue() *waitQueue {
+	return &waitQueue{since: map[orderKey]time.Time{}}
+}
+
+func (w *waitQueue) len() int {
+	return len(w.since)
+}
+
+// add records that key started waiting at at.
+func (w *waitQueue) add(key orderKey, at time.Time) {
+	w.since[key] = at
+
+	// Orders mostly arrive in time order, so this rarely looks past the
+	// back of the queue
+	i := len(w.queue)
+	for i > 0 && w.queue[i-1].at.After(at) {
+		i--
+	}
+	w.queue = slices.Insert(w.queue, i, waiting{key, at})
+
+	if len(w.queue) > 2*len(w.since)+64 {
+		w.queue = slices.DeleteFunc(w.queue, func(o waiting) bool { return !w.waiting(o) })
+	}
+}
+
+// remove stops key waiting, returning when it started.
+func (w *waitQueue) remove(key orderKey) (time.Time, bool) {
+	at, ok := w.since[key]
+	delete(w.since, key)
+	return at, ok
+}
+
+// waiting reports whether o is still waiting, rather than removed or
+// added again since.
+func (w *waitQueue) waiting(o waiting) bool {
+	at, ok := w.since[o.key]
+	return ok && at.Equal(o.at)
+}
+
+// expire removes every order that started waiting before t.
+func (w *waitQueue) expire(t time.Time) {
+	n := 0
+	for ; n < len(w.queue) && w.queue[n].at.Before(t); n++ {
+		if w.waiting(w.queue[n]) {
+			delete(w.since, w.queue[n].key)
+		}
+	}
+	w.queue = w.queue[n:]
+}
+
+// Endpoints returns the names of the endpoints called so far, sorted.
+func (in *Instruments) Endpoints() []string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	names := make([]string, 0, len(in.endpoints))
+	for name := range in.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Endpoint returns the latency of calls to an endpoint, such as "order" or
+// "quote".
+func (in *Instruments) Endpoint(name string) LatencySummary {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	l, ok := in.endpoints[name]
+	if !ok {
+		return LatencySummary{}
+	}
+	return l.summary()
+}
+
+// Reconnects returns the number of attempts to reconnect each websocket
+// stream that has dropped, keyed by stream.
+func (in *Instruments) Reconnects() map[string]Reconnects {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	ret := make(map[string]Reconnects, len(in.reconnects))
+	for stream, r := range in.reconnects {
+		ret[stream] = *r
+	}
+	return ret
+}
+
+// TickLag returns the delay between quotes being timestamped and arriving.
+func (in *Instruments) TickLag() LatencySummary {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.tickLag.summary()
+}
+
+// FillLag returns the delay between executions being timestamped and
+// arriving.
+func (in *Instruments) FillLag() LatencySummary {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.fillLag.summary()
+}
+
+// OrderToFill returns the time from sending an order to receiving its first
+// execution, for orders that have had one.
+func (in *Instruments) OrderToFill() LatencySummary {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.orderToFill.summary()
+}
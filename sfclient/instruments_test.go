@@ -0,0 +1,149 @@
+package sfclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInstrumentsEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			fmt.Fprint(w, `{"ok": true, "venue": "TESTEX", "id": 7, "open": true}`)
+		case "DELETE":
+			fmt.Fprint(w, `{"ok": true, "id": 7}`)
+		default:
+			fmt.Fprint(w, `{"ok": false, "error": "no such venue"}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := New("key", WithBaseURL(srv.URL+"/", "ws://unused/"))
+	in := c.Instruments()
+
+	if _, err := c.BuyOrder(testAccount, testVenue, testSymbol, 100, 10, TypeLimit); err != nil {
+		t.Fatalf("error placing order: %v", err)
+	}
+	if _, err := c.VenueHeartbeat(testVenue); err == nil {
+		t.Error("expected error from heartbeat")
+	}
+
+	if got := in.Endpoints(); len(got) != 2 || got[0] != "order" || got[1] != "venue_heartbeat" {
+		t.Errorf("unexpected endpoints: %v", got)
+	}
+	if s := in.Endpoint("order"); s.Count != 1 || s.Errors != 0 || s.P50 <= 0 || s.P50 > s.Max {
+		t.Errorf("unexpected order latency: %+v", s)
+	}
+	if s := in.Endpoint("venue_heartbeat"); s.Count != 1 || s.Errors != 1 || s.ErrorClasses[ErrClassAPI] != 1 {
+		t.Errorf("expected heartbeat error to be counted: %+v", s)
+	}
+
+	// The order is waiting for its first fill until it is cancelled
+	if in.pending.len() != 1 {
+		t.Errorf("expected order to be pending, got %v", in.pending)
+	}
+	c.CancelOrder(testVenue, testSymbol, 7)
+	if in.pending.len() != 0 {
+		t.Errorf("expected cancel to stop tracking order, got %v", in.pending)
+	}
+}
+
+func TestInstrumentsOrderToFill(t *testing.T) {
+	in := NewInstruments()
+	sent := time.Now()
+
+	fill := func(id, filled, total int, at time.Time) {
+		msg := &FillMessage{Venue: testVenue, Filled: filled, FilledAt: at.Add(-5 * time.Millisecond)}
+		msg.Order.ID, msg.Order.TotalFilled = id, total
+		in.fill(msg, at)
+	}
+
+	// Fills after the order response
+	in.ordered(&OrderResponse{Venue: testVenue, ID: 1, Open: true}, sent)
+	fill(1, 5, 5, sent.Add(20*time.Millisecond))
+	fill(1, 5, 10, sent.Add(30*time.Millisecond))
+
+	// A fill overtaking the order response
+	fill(2, 10, 10, sent.Add(40*time.Millisecond))
+	in.ordered(&OrderResponse{Venue: testVenue, ID: 2, TotalFilled: 10}, sent)
+
+	// A killed order is never tracked
+	in.ordered(&OrderResponse{Venue: testVenue, ID: 3}, sent)
+
+	s := in.OrderToFill()
+	if s.Count != 2 || s.Max != 40*time.Millisecond || s.Mean != 30*time.Millisecond {
+		t.Errorf("unexpected order to fill latency: %+v", s)
+	}
+	if in.pending.len() != 0 || in.early.len() != 0 {
+		t.Errorf("expected nothing left waiting, got %v and %v", in.pending, in.early)
+	}
+
+	if s := in.FillLag(); s.Count != 3 || s.P50 != 5*time.Millisecond {
+		t.Errorf("unexpected fill lag: %+v", s)
+	}
+
+	in.tick(StockState{QuoteTime: sent}, sent.Add(3*time.Millisecond))
+	in.tick(StockState{}, sent)
+	if s := in.TickLag(); s.Count != 1 || s.Max != 3*time.Millisecond {
+		t.Errorf("unexpected tick lag: %+v", s)
+	}
+}
+
+func TestInstrumentsErrorClasses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html>Bad Gateway</html>`)
+	}))
+	c := New("key", WithBaseURL(srv.URL+"/", "ws://unused/"))
+	in := c.Instruments()
+
+	c.Heartbeat()
+	srv.Close()
+	c.Heartbeat()
+	in.request("heartbeat", time.Now(), errors.New("something else"))
+
+	s := in.Endpoint("heartbeat")
+	want := map[string]int{ErrClassDecode: 1, ErrClassTransport: 1, ErrClassOther: 1}
+	if s.Errors != 3 || !reflect.DeepEqual(s.ErrorClasses, want) {
+		t.Errorf("expected errors %v, got %+v", want, s)
+	}
+
+	in.reconnect(StreamTicks, nil)
+	in.reconnect(StreamTicks, errors.New("refused"))
+	in.reconnect(StreamFills, nil)
+	want2 := map[string]Reconnects{StreamTicks: {OK: 1, Failed: 1}, StreamFills: {OK: 1}}
+	if got := in.Reconnects(); !reflect.DeepEqual(got, want2) {
+		t.Errorf("expected reconnects %v, got %v", want2, got)
+	}
+}
+
+func TestInstrumentsExpire(t *testing.T) {
+	in := NewInstruments()
+	sent := time.Now()
+
+	// Orders sent over two hours, one of which is filled and one sent out
+	// of order
+	for i, age := range []time.Duration{120, 90, 100, 70, 30, 10} {
+		in.ordered(&OrderResponse{Venue: testVenue, ID: i + 1, Open: true}, sent.Add(-age*time.Minute))
+	}
+	in.fill(&FillMessage{Venue: testVenue, Order: OrderResponse{ID: 4}}, sent)
+
+	in.mu.Lock()
+	in.expire(sent)
+	in.mu.Unlock()
+
+	// Only those sent in the last hour are left, and the filled order has
+	// left the queue with the others sent before then
+	if in.pending.len() != 2 || len(in.pending.queue) != 2 {
+		t.Errorf("expected 2 orders waiting, got %v", in.pending)
+	}
+	for _, id := range []int{5, 6} {
+		if _, ok := in.pending.since[orderKey{testVenue, id}]; !ok {
+			t.Errorf("expected order %d still waiting", id)
+		}
+	}
+}
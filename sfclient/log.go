@@ -0,0 +1,38 @@
+package sfclient
+
+import "log/slog"
+
+// Keys of the structured fields attached to log records.
+const (
+	LogAccount = "account"
+	LogVenue   = "venue"
+	LogSymbol  = "symbol"
+	LogOrderID = "order_id"
+	LogStream  = "stream"
+)
+
+// Logged is implemented by types carrying a logger, such as *Client and
+// *StockHub. Components built on top of them log through it.
+type Logged interface {
+	Logger() *slog.Logger
+}
+
+// LoggerOf returns the logger of v if it is Logged, or slog.Default.
+func LoggerOf(v any) *slog.Logger {
+	if l, ok := v.(Logged); ok {
+		if logger := l.Logger(); logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// tradingLogger returns logger with fields for the account, venue and, if
+// not empty, symbol.
+func tradingLogger(logger *slog.Logger, account string, venue Venue, stock Symbol) *slog.Logger {
+	logger = logger.With(LogAccount, account, LogVenue, string(venue))
+	if stock != "" {
+		logger = logger.With(LogSymbol, string(stock))
+	}
+	return logger
+}
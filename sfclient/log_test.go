@@ -0,0 +1,41 @@
+package sfclient
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFields(t *testing.T) {
+	var buf bytes.Buffer
+	c := New("key", WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	tl, err := c.StockTicker(testAccount, testVenue, testSymbol)
+	if err != nil {
+		t.Fatalf("error creating ticker: %v", err)
+	}
+	tl.logger.Info("hello")
+	fl, err := c.VenueFills(testAccount, testVenue)
+	if err != nil {
+		t.Fatalf("error creating fills listener: %v", err)
+	}
+	fl.logger.Info("hello")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines logged, got %q", buf.String())
+	}
+	for _, want := range []string{"account=" + testAccount, "venue=" + string(testVenue), "symbol=" + string(testSymbol), "stream=ticks"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("expected %q in %q", want, lines[0])
+		}
+	}
+	if !strings.Contains(lines[1], "stream=fills") || strings.Contains(lines[1], "symbol=") {
+		t.Errorf("expected venue fills without a symbol, got %q", lines[1])
+	}
+
+	if LoggerOf(struct{}{}) != slog.Default() || LoggerOf(c) != c.Logger() {
+		t.Error("unexpected logger from LoggerOf")
+	}
+}
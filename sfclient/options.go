@@ -0,0 +1,32 @@
+package sfclient
+
+import "log/slog"
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another exchange, such as a local test
+// server. api is the base URL of the REST API and ws that of the
+// websockets, both ending in a slash.
+func WithBaseURL(api, ws string) Option {
+	return func(c *Client) {
+		c.baseURL, c.baseWSURL = api, ws
+	}
+}
+
+// WithLogger logs through logger rather than slog.Default. Listeners and
+// hubs created from the client add fields for the account, venue and
+// symbol.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithInstruments records latencies into in rather than a new Instruments,
+// so that several clients can share one.
+func WithInstruments(in *Instruments) Option {
+	return func(c *Client) {
+		c.instruments = in
+	}
+}
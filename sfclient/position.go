@@ -0,0 +1,100 @@
+package sfclient
+
+import "sync"
+
+// Ledger is the position of an account in one stock and the cash traded for
+// it, marked to market. Prices, cash and P&L are in cents. Ledger is not
+// safe for concurrent use; Position keeps one up to date from a StockHub.
+type Ledger struct {
+	// Position is the number of shares held, negative if short
+	Position int
+	Cash     int
+	Bought   int
+	Sold     int
+
+	// Mark is the price the position is valued at: the last traded price,
+	// or the mid if nothing has traded. Until a quote has either it is the
+	// price of the first fill.
+	Mark int
+}
+
+// Quote marks the position from q.
+func (l *Ledger) Quote(q StockState) {
+	switch {
+	case q.Last > 0:
+		l.Mark = q.Last
+	case q.Bid > 0 && q.Ask > 0:
+		l.Mark = (q.Bid + q.Ask) / 2
+	}
+}
+
+// Fill records that qty shares were bought or sold at price.
+func (l *Ledger) Fill(isBuy bool, price, qty int) {
+	if isBuy {
+		l.Position += qty
+		l.Bought += qty
+		l.Cash -= price * qty
+	} else {
+		l.Position -= qty
+		l.Sold += qty
+		l.Cash += price * qty
+	}
+	if l.Mark == 0 {
+		l.Mark = price
+	}
+}
+
+// PnL returns the cash plus the position valued at the mark.
+func (l *Ledger) PnL() int {
+	return l.Cash + l.Position*l.Mark
+}
+
+// Position is a StockHub component keeping a Ledger of the account's fills
+// in the hub's stock, marked from its ticks.
+type Position struct {
+	ticks chan *TickMessage
+	fills chan *FillMessage
+
+	mu     *sync.Mutex
+	ledger Ledger
+}
+
+func NewPosition() *Position {
+	return &Position{
+		ticks: make(chan *TickMessage, 100),
+		fills: make(chan *FillMessage, 100),
+		mu:    &sync.Mutex{},
+	}
+}
+
+func (p *Position) Register(hub *StockHub) {
+	hub.RegisterToTick(p.ticks)
+	hub.RegisterToFills(p.fills)
+	go p.init()
+}
+
+func (p *Position) init() {
+	for {
+		select {
+		case msg := <-p.ticks:
+			if msg.OK {
+				p.mu.Lock()
+				p.ledger.Quote(msg.Quote)
+				p.mu.Unlock()
+			}
+		case msg := <-p.fills:
+			if msg.OK {
+				p.mu.Lock()
+				p.ledger.Fill(msg.Order.Direction == "buy", msg.Price, msg.Filled)
+				p.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Ledger returns the current position.
+func (p *Position) Ledger() Ledger {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ledger
+}
@@ -0,0 +1,31 @@
+package sfclient
+
+import "testing"
+
+func TestLedger(t *testing.T) {
+	var l Ledger
+
+	// Marked at the first fill until there is a quote
+	l.Fill(true, 100, 10)
+	if l.Mark != 100 || l.PnL() != 0 {
+		t.Errorf("expected a mark of 100 and no P&L, got %+v", l)
+	}
+
+	l.Quote(StockState{Bid: 104, Ask: 108})
+	if l.Mark != 106 || l.PnL() != 60 {
+		t.Errorf("expected to mark at the mid 106 for P&L 60, got %+v", l)
+	}
+
+	l.Fill(false, 110, 4)
+	l.Quote(StockState{Bid: 104, Ask: 108, Last: 105})
+	want := Ledger{Position: 6, Cash: -560, Bought: 10, Sold: 4, Mark: 105}
+	if l != want || l.PnL() != 70 {
+		t.Errorf("expected %+v with P&L 70, got %+v with %d", want, l, l.PnL())
+	}
+
+	// A one sided quote leaves the mark alone
+	l.Quote(StockState{Bid: 90})
+	if l.Mark != 105 {
+		t.Errorf("expected the mark to stay at 105, got %d", l.Mark)
+	}
+}
@@ -0,0 +1,41 @@
+package sfclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePrice converts a price in dollars, such as "100.25", to cents.
+func ParsePrice(s string) (int, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return int(f*100 + 0.5), nil
+}
+
+// FormatPrice formats a price or amount in cents as dollars.
+func FormatPrice(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// ParseOrderType accepts an order type by its name or abbreviation, such as
+// "limit" or "ioc".
+func ParseOrderType(s string) (OrderType, error) {
+	switch strings.ToLower(s) {
+	case "limit":
+		return TypeLimit, nil
+	case "market":
+		return TypeMarket, nil
+	case "fok", "fill-or-kill":
+		return TypeFillOrKill, nil
+	case "ioc", "immediate-or-cancel":
+		return TypeImmediateOrCancel, nil
+	}
+	return "", fmt.Errorf("invalid order type %q, expected limit, market, fok or ioc", s)
+}
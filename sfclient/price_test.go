@@ -0,0 +1,33 @@
+package sfclient
+
+import "testing"
+
+func TestPrice(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want int
+	}{{"100.25", 10025}, {"0.01", 1}, {"42", 4200}, {"19.99", 1999}} {
+		got, err := ParsePrice(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("ParsePrice(%q) = %d, %v; want %d", tc.in, got, err, tc.want)
+		}
+		if s := FormatPrice(tc.want); s != FormatPrice(got) {
+			t.Errorf("FormatPrice(%d) = %q", tc.want, s)
+		}
+	}
+	for _, bad := range []string{"", "abc", "-1", "0"} {
+		if _, err := ParsePrice(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+	if s := FormatPrice(-1005); s != "-10.05" {
+		t.Errorf("expected -10.05, got %q", s)
+	}
+
+	if typ, err := ParseOrderType("IOC"); err != nil || typ != TypeImmediateOrCancel {
+		t.Errorf("unexpected ioc type %q, %v", typ, err)
+	}
+	if _, err := ParseOrderType("gtc"); err == nil {
+		t.Error("expected error parsing gtc")
+	}
+}
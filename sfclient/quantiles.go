@@ -0,0 +1,100 @@
+package sfclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/quantest"
+)
+
+// DefaultQuantiles tracks the median and the 10th and 90th percentiles.
+var DefaultQuantiles = []quantest.Quantile{
+	quantest.NewQuantile(0.1, 0.01),
+	quantest.NewQuantile(0.5, 0.01),
+	quantest.NewQuantile(0.9, 0.01),
+}
+
+// QuoteQuantiles is a StockHub component estimating quantiles of the bid,
+// ask, last trade price, spread and mid price since it was registered,
+// using CKMS sketches. Quantiles are robust to the outliers that skew
+// averages, so make better price guards.
+type QuoteQuantiles struct {
+	ch chan *TickMessage
+
+	mu        *sync.Mutex
+	ests      [numFields]*quantest.CKMS[int]
+	counts    [numFields]int
+	lastTrade time.Time
+}
+
+// NewQuoteQuantiles creates a component targeting the given quantiles.
+// Queries for other quantiles are answered, but without an error bound.
+func NewQuoteQuantiles(quantiles []quantest.Quantile) *QuoteQuantiles {
+	q := &QuoteQuantiles{ch: make(chan *TickMessage, 100), mu: &sync.Mutex{}}
+	for i := range q.ests {
+		q.ests[i] = quantest.NewCKMS[int](quantiles)
+	}
+	return q
+}
+
+func (q *QuoteQuantiles) Register(hub *StockHub) {
+	hub.RegisterToTick(q.ch)
+	go q.init()
+}
+
+func (q *QuoteQuantiles) init() {
+	for msg := range q.ch {
+		if msg.OK {
+			q.Add(msg.Quote)
+		}
+	}
+}
+
+func (q *QuoteQuantiles) insert(f Field, v int) {
+	q.ests[f].Insert(v)
+	q.counts[f]++
+}
+
+// Add samples a quote. The last price is only sampled once per trade.
+func (q *QuoteQuantiles) Add(s StockState) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if s.Bid > 0 {
+		q.insert(FieldBid, s.Bid)
+	}
+	if s.Ask > 0 {
+		q.insert(FieldAsk, s.Ask)
+	}
+	if s.Bid > 0 && s.Ask > 0 {
+		q.insert(FieldSpread, s.Ask-s.Bid)
+		q.insert(FieldMid, (s.Ask+s.Bid)/2)
+	}
+	if s.LastSize > 0 && s.LastTrade.After(q.lastTrade) {
+		q.lastTrade = s.LastTrade
+		q.insert(FieldLast, s.Last)
+	}
+}
+
+// Quantile estimates the given quantile of f. ok is false if f has not been
+// sampled yet.
+func (q *QuoteQuantiles) Quantile(f Field, quantile float64) (v int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.counts[f] == 0 {
+		return 0, false
+	}
+	return q.ests[f].Query(quantile), true
+}
+
+func (q *QuoteQuantiles) Median(f Field) (int, bool) {
+	return q.Quantile(f, 0.5)
+}
+
+// Count returns the number of samples of f.
+func (q *QuoteQuantiles) Count(f Field) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.counts[f]
+}
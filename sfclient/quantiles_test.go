@@ -0,0 +1,55 @@
+package sfclient
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestQuoteQuantiles(t *testing.T) {
+	const n = 50000
+	q := NewQuoteQuantiles(DefaultQuantiles)
+
+	if _, ok := q.Median(FieldAsk); ok {
+		t.Error("expected no median before any quotes")
+	}
+
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+	asks := make([]int, n)
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := 0; i < n; i++ {
+		bid := 5000 + int(rng.NormFloat64()*100)
+		spread := 1 + int(rng.ExpFloat64()*20)
+		asks[i] = bid + spread
+		at := start.Add(time.Duration(i) * time.Millisecond)
+		q.Add(StockState{Bid: bid, Ask: bid + spread, Last: bid, LastSize: 1, LastTrade: at, QuoteTime: at})
+	}
+
+	sort.Ints(asks)
+
+	// Same accuracy measure as TestCKMS: error relative to the true value
+	for _, quantile := range DefaultQuantiles {
+		est, ok := q.Quantile(FieldAsk, quantile.Q())
+		if !ok {
+			t.Fatal("no ask quantile")
+		}
+		actual := asks[int(n*quantile.Q())-1]
+		if err := math.Abs(float64(est-actual)) / float64(actual); err > quantile.ErrFrac() {
+			t.Errorf("%s: ask estimate=%d, actual=%d, off=%.4f", quantile, est, actual, err)
+		}
+	}
+
+	p10, _ := q.Quantile(FieldSpread, 0.1)
+	p50, _ := q.Median(FieldSpread)
+	p90, _ := q.Quantile(FieldSpread, 0.9)
+	if !(p10 <= p50 && p50 <= p90) || p50 <= 0 {
+		t.Errorf("spread quantiles out of order: p10=%d p50=%d p90=%d", p10, p50, p90)
+	}
+
+	if q.Count(FieldLast) != n {
+		t.Errorf("expected %d trades, got %d", n, q.Count(FieldLast))
+	}
+}
@@ -0,0 +1,276 @@
+// Package sftest provides an in-memory exchange for testing code built on
+// sfclient without connecting to Stockfighter.
+package sftest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Market is a fake exchange satisfying both sfclient.MarketData and
+// sfclient.OrderGateway. Orders are acknowledged and recorded but never
+// matched: tests drive executions with PushFill.
+type Market struct {
+	mu     *sync.Mutex
+	quote  sfclient.StockState
+	book   *sfclient.StockOrderBookResponse
+	ticks  []*TickSource
+	fills  []*FillSource
+	orders []*sfclient.OrderResponse
+	nextID int
+}
+
+var (
+	_ sfclient.MarketData   = (*Market)(nil)
+	_ sfclient.OrderGateway = (*Market)(nil)
+)
+
+func NewMarket() *Market {
+	return &Market{mu: &sync.Mutex{}, book: &sfclient.StockOrderBookResponse{APIResponse: sfclient.APIResponse{OK: true}}}
+}
+
+// SetBook sets the order book returned by StockOrderBook.
+func (m *Market) SetBook(book *sfclient.StockOrderBookResponse) {
+	m.mu.Lock()
+	m.book = book
+	m.mu.Unlock()
+}
+
+// PushTick sets the current quote and sends msg to every listening tick
+// source.
+func (m *Market) PushTick(msg *sfclient.TickMessage) {
+	m.mu.Lock()
+	m.quote = msg.Quote
+	sources := append([]*TickSource(nil), m.ticks...)
+	m.mu.Unlock()
+
+	for _, s := range sources {
+		s.send(msg)
+	}
+}
+
+// PushFill sends msg to every listening fill source.
+func (m *Market) PushFill(msg *sfclient.FillMessage) {
+	m.mu.Lock()
+	sources := append([]*FillSource(nil), m.fills...)
+	m.mu.Unlock()
+
+	for _, s := range sources {
+		s.send(msg)
+	}
+}
+
+// Orders returns every order placed so far, including cancelled ones.
+func (m *Market) Orders() []*sfclient.OrderResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := make([]*sfclient.OrderResponse, len(m.orders))
+	for i, o := range m.orders {
+		cp := *o
+		ret[i] = &cp
+	}
+	return ret
+}
+
+// Open returns the orders that have not been cancelled.
+func (m *Market) Open() []*sfclient.OrderResponse {
+	var open []*sfclient.OrderResponse
+	for _, o := range m.Orders() {
+		if o.Open {
+			open = append(open, o)
+		}
+	}
+	return open
+}
+
+func (m *Market) Quote(venue sfclient.Venue, stock sfclient.Symbol) (*sfclient.QuoteResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &sfclient.QuoteResponse{APIResponse: sfclient.APIResponse{OK: true}, StockState: m.quote}, nil
+}
+
+func (m *Market) StockOrderBook(venue sfclient.Venue, stock sfclient.Symbol) (*sfclient.StockOrderBookResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *m.book
+	return &cp, nil
+}
+
+func (m *Market) StockTickSource(account string, venue sfclient.Venue, stock sfclient.Symbol) (sfclient.TickSource, error) {
+	s := &TickSource{newStream[*sfclient.TickMessage]()}
+	m.mu.Lock()
+	m.ticks = append(m.ticks, s)
+	m.mu.Unlock()
+	return s, nil
+}
+
+func (m *Market) StockFillSource(account string, venue sfclient.Venue, stock sfclient.Symbol) (sfclient.FillSource, error) {
+	s := &FillSource{newStream[*sfclient.FillMessage]()}
+	m.mu.Lock()
+	m.fills = append(m.fills, s)
+	m.mu.Unlock()
+	return s, nil
+}
+
+func (m *Market) order(account string, venue sfclient.Venue, stock sfclient.Symbol, price, quantity int, orderType sfclient.OrderType, direction string) (*sfclient.OrderResponse, error) {
+	if quantity <= 0 {
+		return nil, errors.New("invalid quantity")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	o := &sfclient.OrderResponse{
+		APIResponse:      sfclient.APIResponse{OK: true},
+		Symbol:           stock,
+		Venue:            venue,
+		Direction:        direction,
+		OriginalQuantity: quantity,
+		Quantity:         quantity,
+		Price:            price,
+		OrderType:        string(orderType),
+		ID:               m.nextID,
+		Account:          account,
+		Timestamp:        time.Now(),
+		Open:             true,
+	}
+	m.orders = append(m.orders, o)
+
+	cp := *o
+	return &cp, nil
+}
+
+func (m *Market) BuyOrder(account string, venue sfclient.Venue, stock sfclient.Symbol, price int, quantity int, orderType sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return m.order(account, venue, stock, price, quantity, orderType, "buy")
+}
+
+func (m *Market) SellOrder(account string, venue sfclient.Venue, stock sfclient.Symbol, price int, quantity int, orderType sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return m.order(account, venue, stock, price, quantity, orderType, "sell")
+}
+
+func (m *Market) find(id int) (*sfclient.OrderResponse, error) {
+	for _, o := range m.orders {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	return nil, errors.New("no such order")
+}
+
+func toState(o *sfclient.OrderResponse) sfclient.OrderState {
+	return sfclient.OrderState{
+		Symbol:           o.Symbol,
+		Venue:            o.Venue,
+		Direction:        o.Direction,
+		OriginalQuantity: o.OriginalQuantity,
+		Quantity:         o.Quantity,
+		Price:            o.Price,
+		OrderType:        sfclient.OrderType(o.OrderType),
+		ID:               o.ID,
+		Account:          o.Account,
+		Timestamp:        o.Timestamp,
+		Fills:            o.Fills,
+		TotalFilled:      o.TotalFilled,
+		Open:             o.Open,
+	}
+}
+
+func (m *Market) CancelOrder(venue sfclient.Venue, stock sfclient.Symbol, id int) (*sfclient.CancelOrderResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	o.Open, o.Quantity = false, 0
+	return &sfclient.CancelOrderResponse{APIResponse: sfclient.APIResponse{OK: true}, OrderState: toState(o)}, nil
+}
+
+func (m *Market) OrderStatus(venue sfclient.Venue, stock sfclient.Symbol, id int) (*sfclient.StatusResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return &sfclient.StatusResponse{APIResponse: sfclient.APIResponse{OK: true}, OrderState: toState(o)}, nil
+}
+
+func (m *Market) StockOrdersStatus(account string, venue sfclient.Venue, stock sfclient.Symbol) (*sfclient.MultiStatusResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resp := &sfclient.MultiStatusResponse{APIResponse: sfclient.APIResponse{OK: true}}
+	for _, o := range m.orders {
+		resp.Orders = append(resp.Orders, toState(o))
+	}
+	return resp, nil
+}
+
+// TickSource is an in-memory sfclient.TickSource fed by Market.PushTick.
+type TickSource struct {
+	stream[*sfclient.TickMessage]
+}
+
+// FillSource is an in-memory sfclient.FillSource fed by Market.PushFill.
+type FillSource struct {
+	stream[*sfclient.FillMessage]
+}
+
+// stream delivers messages to a listener until closed. A send blocks while
+// the listener is behind, but never holds the lock while it does, so Close
+// can always interrupt it.
+type stream[T any] struct {
+	mu       sync.Mutex
+	closed   bool
+	sending  sync.WaitGroup
+	done     chan struct{}
+	messages chan T
+}
+
+func newStream[T any]() stream[T] {
+	return stream[T]{done: make(chan struct{}), messages: make(chan T, 1000)}
+}
+
+func (s *stream[T]) Listen() (<-chan T, error) {
+	return s.messages, nil
+}
+
+func (s *stream[T]) send(msg T) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.sending.Add(1)
+	s.mu.Unlock()
+	defer s.sending.Done()
+
+	select {
+	case s.messages <- msg:
+	case <-s.done:
+	}
+}
+
+// Close stops the stream, dropping any message still being sent, and
+// closes the channel returned by Listen.
+func (s *stream[T]) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	s.sending.Wait()
+	close(s.messages)
+}
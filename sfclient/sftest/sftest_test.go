@@ -0,0 +1,51 @@
+package sftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+func TestCloseInterruptsSend(t *testing.T) {
+	m := NewMarket()
+	src, err := m.StockTickSource("EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating tick source: %v", err)
+	}
+	ticks, _ := src.Listen()
+
+	// Fill the buffer so the next push blocks on the listener
+	msg := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	for i := 0; i < cap(ticks); i++ {
+		m.PushTick(msg)
+	}
+	pushed := make(chan struct{})
+	go func() {
+		defer close(pushed)
+		m.PushTick(msg)
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		src.Close()
+	}()
+
+	for _, ch := range []chan struct{}{closed, pushed} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("close blocked behind a send to a full listener")
+		}
+	}
+
+	n := 0
+	for range ticks {
+		n++
+	}
+	if n < cap(ticks) {
+		t.Errorf("expected the %d buffered ticks to be delivered, got %d", cap(ticks), n)
+	}
+	m.PushTick(msg)
+}
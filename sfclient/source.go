@@ -0,0 +1,49 @@
+package sfclient
+
+// TickSource is a stream of ticks. *TickListener satisfies it.
+type TickSource interface {
+	Listen() (<-chan *TickMessage, error)
+	Close()
+}
+
+// FillSource is a stream of executions. *FillListener satisfies it.
+type FillSource interface {
+	Listen() (<-chan *FillMessage, error)
+	Close()
+}
+
+// MarketData provides public market information for a stock. *Client
+// satisfies it.
+type MarketData interface {
+	Quote(venue Venue, stock Symbol) (*QuoteResponse, error)
+	StockOrderBook(venue Venue, stock Symbol) (*StockOrderBookResponse, error)
+	StockTickSource(account string, venue Venue, stock Symbol) (TickSource, error)
+}
+
+// OrderGateway places and tracks orders for an account, and reports their
+// executions. *Client satisfies it.
+type OrderGateway interface {
+	BuyOrder(account string, venue Venue, stock Symbol, price int, quantity int, orderType OrderType) (*OrderResponse, error)
+	SellOrder(account string, venue Venue, stock Symbol, price int, quantity int, orderType OrderType) (*OrderResponse, error)
+	CancelOrder(venue Venue, stock Symbol, id int) (*CancelOrderResponse, error)
+	OrderStatus(venue Venue, stock Symbol, id int) (*StatusResponse, error)
+	StockOrdersStatus(account string, venue Venue, stock Symbol) (*MultiStatusResponse, error)
+	StockFillSource(account string, venue Venue, stock Symbol) (FillSource, error)
+}
+
+var (
+	_ MarketData   = (*Client)(nil)
+	_ OrderGateway = (*Client)(nil)
+)
+
+// StockTickSource is StockTicker returning the TickSource interface, for use
+// as MarketData.
+func (c *Client) StockTickSource(account string, venue Venue, stock Symbol) (TickSource, error) {
+	return c.StockTicker(account, venue, stock)
+}
+
+// StockFillSource is StockFills returning the FillSource interface, for use
+// as an OrderGateway.
+func (c *Client) StockFillSource(account string, venue Venue, stock Symbol) (FillSource, error) {
+	return c.StockFills(account, venue, stock)
+}
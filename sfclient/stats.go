@@ -0,0 +1,346 @@
+package sfclient
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// WindowSize bounds a rolling window by number of samples, by age relative
+// to the newest sample, or both. A zero field is unbounded.
+type WindowSize struct {
+	Count int
+	Age   time.Duration
+}
+
+type sample struct {
+	seq int
+	t   time.Time
+	v   float64
+}
+
+// Window holds a rolling series of values. Adding a value and querying the
+// sum, mean, variance, minimum and maximum are all O(1) amortised.
+type Window struct {
+	size WindowSize
+
+	// samples is a queue; samples[head:] are live
+	samples []sample
+	head    int
+	seq     int
+
+	sum   float64
+	sumSq float64
+
+	// monotonic queues of candidates for the minimum and maximum
+	mins []sample
+	maxs []sample
+
+	// evictions since the sums were last recomputed from scratch, to stop
+	// floating point error accumulating
+	drift int
+}
+
+func NewWindow(size WindowSize) *Window {
+	return &Window{size: size}
+}
+
+// Add appends v, observed at t, evicting any samples that fall out of the
+// window. Samples must be added in time order.
+func (w *Window) Add(t time.Time, v float64) {
+	s := sample{seq: w.seq, t: t, v: v}
+	w.seq++
+
+	w.samples = append(w.samples, s)
+	w.sum += v
+	w.sumSq += v * v
+
+	for len(w.mins) > 0 && w.mins[len(w.mins)-1].v >= v {
+		w.mins = w.mins[:len(w.mins)-1]
+	}
+	w.mins = append(w.mins, s)
+	for len(w.maxs) > 0 && w.maxs[len(w.maxs)-1].v <= v {
+		w.maxs = w.maxs[:len(w.maxs)-1]
+	}
+	w.maxs = append(w.maxs, s)
+
+	w.evict(t)
+}
+
+func (w *Window) evict(now time.Time) {
+	for w.Len() > 0 {
+		oldest := w.samples[w.head]
+		tooMany := w.size.Count > 0 && w.Len() > w.size.Count
+		tooOld := w.size.Age > 0 && now.Sub(oldest.t) > w.size.Age
+		if !tooMany && !tooOld {
+			break
+		}
+
+		w.head++
+		w.sum -= oldest.v
+		w.sumSq -= oldest.v * oldest.v
+		w.drift++
+
+		if w.mins[0].seq == oldest.seq {
+			w.mins = w.mins[1:]
+		}
+		if w.maxs[0].seq == oldest.seq {
+			w.maxs = w.maxs[1:]
+		}
+	}
+
+	// Reclaim the evicted prefix once it dominates the backing array
+	if w.head > 1024 && w.head > len(w.samples)/2 {
+		w.samples = append(w.samples[:0], w.samples[w.head:]...)
+		w.head = 0
+	}
+
+	if w.drift > 1024 && w.drift > w.Len() {
+		w.sum, w.sumSq = 0, 0
+		for _, s := range w.samples[w.head:] {
+			w.sum += s.v
+			w.sumSq += s.v * s.v
+		}
+		w.drift = 0
+	}
+}
+
+func (w *Window) Len() int {
+	return len(w.samples) - w.head
+}
+
+func (w *Window) Sum() float64 {
+	return w.sum
+}
+
+// Mean returns the mean of the window, or 0 if it is empty.
+func (w *Window) Mean() float64 {
+	if w.Len() == 0 {
+		return 0
+	}
+	return w.sum / float64(w.Len())
+}
+
+// Variance returns the population variance of the window.
+func (w *Window) Variance() float64 {
+	n := float64(w.Len())
+	if n == 0 {
+		return 0
+	}
+	mean := w.sum / n
+	// Guard against tiny negative results from rounding
+	return math.Max(0, w.sumSq/n-mean*mean)
+}
+
+func (w *Window) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+// Min returns the smallest value in the window, or 0 if it is empty.
+func (w *Window) Min() float64 {
+	if len(w.mins) == 0 {
+		return 0
+	}
+	return w.mins[0].v
+}
+
+// Max returns the largest value in the window, or 0 if it is empty.
+func (w *Window) Max() float64 {
+	if len(w.maxs) == 0 {
+		return 0
+	}
+	return w.maxs[0].v
+}
+
+// Last returns the newest value in the window, or 0 if it is empty.
+func (w *Window) Last() float64 {
+	if w.Len() == 0 {
+		return 0
+	}
+	return w.samples[len(w.samples)-1].v
+}
+
+// Values returns the window oldest first.
+func (w *Window) Values() []float64 {
+	vals := make([]float64, 0, w.Len())
+	for _, s := range w.samples[w.head:] {
+		vals = append(vals, s.v)
+	}
+	return vals
+}
+
+// EWMA is an exponentially weighted moving average. Each new value is given
+// weight alpha, so smaller alphas average over a longer history.
+type EWMA struct {
+	alpha float64
+	value float64
+	init  bool
+}
+
+func NewEWMA(alpha float64) *EWMA {
+	return &EWMA{alpha: alpha}
+}
+
+func (e *EWMA) Add(v float64) {
+	if !e.init {
+		e.value, e.init = v, true
+		return
+	}
+	e.value += e.alpha * (v - e.value)
+}
+
+func (e *EWMA) Value() float64 {
+	return e.value
+}
+
+// Field is a price series derived from the tickertape.
+type Field int
+
+const (
+	FieldBid Field = iota
+	FieldAsk
+	FieldLast
+	FieldSpread
+	FieldMid
+	numFields
+)
+
+func (f Field) String() string {
+	switch f {
+	case FieldBid:
+		return "bid"
+	case FieldAsk:
+		return "ask"
+	case FieldLast:
+		return "last"
+	case FieldSpread:
+		return "spread"
+	case FieldMid:
+		return "mid"
+	}
+	return "unknown"
+}
+
+// Summary is a consistent snapshot of the statistics of one field.
+type Summary struct {
+	Count    int
+	Last     float64
+	Mean     float64
+	EWMA     float64
+	Variance float64
+	StdDev   float64
+	Min      float64
+	Max      float64
+}
+
+// MarketStats is a StockHub component keeping rolling statistics of the
+// bid, ask, last trade price, spread and mid price. Bid, ask, spread and mid
+// are sampled on every tick that has a price for them; the last price is
+// sampled once per trade.
+type MarketStats struct {
+	ch chan *TickMessage
+
+	mu        *sync.Mutex
+	windows   [numFields]*Window
+	ewmas     [numFields]*EWMA
+	tradePV   *Window
+	tradeQty  *Window
+	lastTrade time.Time
+}
+
+// NewMarketStats keeps statistics over windows of the given size, with
+// EWMAs using alpha.
+func NewMarketStats(size WindowSize, alpha float64) *MarketStats {
+	s := &MarketStats{
+		ch:       make(chan *TickMessage, 100),
+		mu:       &sync.Mutex{},
+		tradePV:  NewWindow(size),
+		tradeQty: NewWindow(size),
+	}
+	for i := range s.windows {
+		s.windows[i] = NewWindow(size)
+		s.ewmas[i] = NewEWMA(alpha)
+	}
+	return s
+}
+
+func (s *MarketStats) Register(hub *StockHub) {
+	hub.RegisterToTick(s.ch)
+	go s.init()
+}
+
+func (s *MarketStats) init() {
+	for msg := range s.ch {
+		if msg.OK {
+			s.Add(msg.Quote)
+		}
+	}
+}
+
+func (s *MarketStats) add(f Field, t time.Time, v float64) {
+	s.windows[f].Add(t, v)
+	s.ewmas[f].Add(v)
+}
+
+// Add samples a quote.
+func (s *MarketStats) Add(q StockState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := q.QuoteTime
+	if q.Bid > 0 {
+		s.add(FieldBid, t, float64(q.Bid))
+	}
+	if q.Ask > 0 {
+		s.add(FieldAsk, t, float64(q.Ask))
+	}
+	if q.Bid > 0 && q.Ask > 0 {
+		s.add(FieldSpread, t, float64(q.Ask-q.Bid))
+		s.add(FieldMid, t, float64(q.Ask+q.Bid)/2)
+	}
+
+	if q.LastSize > 0 && q.LastTrade.After(s.lastTrade) {
+		s.lastTrade = q.LastTrade
+		s.add(FieldLast, q.LastTrade, float64(q.Last))
+		s.tradePV.Add(q.LastTrade, float64(q.Last*q.LastSize))
+		s.tradeQty.Add(q.LastTrade, float64(q.LastSize))
+	}
+}
+
+// Summary returns the current statistics for f.
+func (s *MarketStats) Summary(f Field) Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.windows[f]
+	return Summary{
+		Count:    w.Len(),
+		Last:     w.Last(),
+		Mean:     w.Mean(),
+		EWMA:     s.ewmas[f].Value(),
+		Variance: w.Variance(),
+		StdDev:   w.StdDev(),
+		Min:      w.Min(),
+		Max:      w.Max(),
+	}
+}
+
+// Series returns the values of f in the window, oldest first.
+func (s *MarketStats) Series(f Field) []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.windows[f].Values()
+}
+
+// VWAP returns the volume weighted average price of the trades in the
+// window. ok is false if there have been none.
+func (s *MarketStats) VWAP() (vwap float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	qty := s.tradeQty.Sum()
+	if qty == 0 {
+		return 0, false
+	}
+	return s.tradePV.Sum() / qty, true
+}
@@ -0,0 +1,117 @@
+package sfclient
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
+
+func TestWindowCount(t *testing.T) {
+	const size = 100
+	w := NewWindow(WindowSize{Count: size})
+	var all []float64
+
+	rng := rand.New(rand.NewSource(0xDEADBEEF))
+	for i := 0; i < 5000; i++ {
+		v := float64(rng.Int31n(10000))
+		all = append(all, v)
+		w.Add(time.Time{}, v)
+
+		window := all[max(0, len(all)-size):]
+		var sum, sumSq float64
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, x := range window {
+			sum += x
+			sumSq += x * x
+			lo, hi = math.Min(lo, x), math.Max(hi, x)
+		}
+		n := float64(len(window))
+		mean := sum / n
+
+		if w.Len() != len(window) {
+			t.Fatalf("%d: expected length %d, got %d", i, len(window), w.Len())
+		}
+		if !near(w.Mean(), mean) || !near(w.Variance(), sumSq/n-mean*mean) {
+			t.Fatalf("%d: expected mean %f variance %f, got %f %f", i, mean, sumSq/n-mean*mean, w.Mean(), w.Variance())
+		}
+		if w.Min() != lo || w.Max() != hi {
+			t.Fatalf("%d: expected min %f max %f, got %f %f", i, lo, hi, w.Min(), w.Max())
+		}
+	}
+}
+
+func TestWindowAge(t *testing.T) {
+	w := NewWindow(WindowSize{Age: 10 * time.Second})
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 30; i++ {
+		w.Add(start.Add(time.Duration(i)*time.Second), float64(i))
+	}
+
+	// Samples from 19s to 29s inclusive are within 10s of the newest
+	if w.Len() != 11 || w.Min() != 19 || w.Max() != 29 || w.Mean() != 24 {
+		t.Errorf("unexpected window: len=%d min=%f max=%f mean=%f", w.Len(), w.Min(), w.Max(), w.Mean())
+	}
+
+	vals := w.Values()
+	if len(vals) != 11 || vals[0] != 19 || vals[10] != 29 {
+		t.Errorf("unexpected values: %v", vals)
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	e := NewEWMA(0.5)
+	for _, v := range []float64{10, 20, 20} {
+		e.Add(v)
+	}
+	if e.Value() != 17.5 {
+		t.Errorf("expected 17.5, got %f", e.Value())
+	}
+}
+
+func TestMarketStats(t *testing.T) {
+	s := NewMarketStats(WindowSize{Count: 10}, 0.1)
+	start := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := s.VWAP(); ok {
+		t.Error("expected no VWAP without trades")
+	}
+
+	s.Add(StockState{Bid: 98, Ask: 102, Last: 100, LastSize: 10, LastTrade: start, QuoteTime: start})
+	// No bid, and the same trade reported again
+	s.Add(StockState{Ask: 104, Last: 100, LastSize: 10, LastTrade: start, QuoteTime: start.Add(time.Second)})
+	s.Add(StockState{Bid: 99, Ask: 101, Last: 103, LastSize: 30, LastTrade: start.Add(2 * time.Second), QuoteTime: start.Add(2 * time.Second)})
+
+	if sum := s.Summary(FieldBid); sum.Count != 2 || sum.Mean != 98.5 {
+		t.Errorf("unexpected bid summary: %+v", sum)
+	}
+	if sum := s.Summary(FieldAsk); sum.Count != 3 || sum.Max != 104 || sum.Last != 101 {
+		t.Errorf("unexpected ask summary: %+v", sum)
+	}
+	if sum := s.Summary(FieldSpread); sum.Count != 2 || sum.Min != 2 || sum.Max != 4 {
+		t.Errorf("unexpected spread summary: %+v", sum)
+	}
+	if mids := s.Series(FieldMid); len(mids) != 2 || mids[0] != 100 || mids[1] != 100 {
+		t.Errorf("unexpected mids: %v", mids)
+	}
+	if sum := s.Summary(FieldLast); sum.Count != 2 {
+		t.Errorf("expected 2 trades, got %d", sum.Count)
+	}
+
+	vwap, ok := s.VWAP()
+	if want := float64(100*10+103*30) / 40; !ok || vwap != want {
+		t.Errorf("expected vwap %f, got %f", want, vwap)
+	}
+}
+
+func TestBidAskHistoryAvgEmpty(t *testing.T) {
+	h := NewBidAskHistory(10)
+	if ask, bid := h.Avg(10); ask != 0 || bid != 0 {
+		t.Errorf("expected zero averages, got %d %d", ask, bid)
+	}
+}
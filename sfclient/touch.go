@@ -0,0 +1,82 @@
+package sfclient
+
+import "time"
+
+// Touch tracks the liquidity at the top of the book that simulated orders
+// have taken from successive quotes and trades, so the same displayed size
+// or printed trade is never executed against twice. Size taken at a price
+// stays taken for as long as that price is the best; only size displayed
+// beyond it is offered again.
+//
+// Touch is not safe for concurrent use.
+type Touch struct {
+	quote    StockState
+	bidTaken int
+	askTaken int
+
+	// The latest execution printed, and how much of it is yet to be
+	// allotted to resting orders
+	printedAt  time.Time
+	tradePrice int
+	tradeLeft  int
+}
+
+// Quote returns the latest quote.
+func (t *Touch) Quote() StockState {
+	return t.quote
+}
+
+// Update moves the touch on to q. Its last trade is printed if it is newer
+// than any already printed; otherwise what is left of the previous print is
+// withdrawn, as it has already been offered to every resting order.
+func (t *Touch) Update(q StockState) {
+	if q.Bid != t.quote.Bid {
+		t.bidTaken = 0
+	}
+	if q.Ask != t.quote.Ask {
+		t.askTaken = 0
+	}
+	t.quote = q
+
+	if q.LastSize > 0 && q.LastTrade.After(t.printedAt) {
+		t.Print(q.LastTrade, q.Last, q.LastSize)
+	} else {
+		t.tradeLeft = 0
+	}
+}
+
+// Print records an execution of qty at price. A quote whose last trade is
+// no later than at is taken to be showing this same execution.
+func (t *Touch) Print(at time.Time, price, qty int) {
+	t.printedAt, t.tradePrice, t.tradeLeft = at, price, qty
+}
+
+// Offered returns the best price on the side an order takes from, the ask
+// for a buy and the bid for a sell, and how much of its displayed size is
+// yet to be taken. The price is 0 if that side is empty.
+func (t *Touch) Offered(isBuy bool) (price, qty int) {
+	if isBuy {
+		return t.quote.Ask, max(0, t.quote.AskSize-t.askTaken)
+	}
+	return t.quote.Bid, max(0, t.quote.BidSize-t.bidTaken)
+}
+
+// Take removes qty from the size Offered to an order on the isBuy side.
+func (t *Touch) Take(isBuy bool, qty int) {
+	if isBuy {
+		t.askTaken += qty
+	} else {
+		t.bidTaken += qty
+	}
+}
+
+// Traded returns the time and price of the latest execution and how much
+// of it is yet to be allotted.
+func (t *Touch) Traded() (at time.Time, price, qty int) {
+	return t.printedAt, t.tradePrice, t.tradeLeft
+}
+
+// Allot removes qty from what is left of the latest execution.
+func (t *Touch) Allot(qty int) {
+	t.tradeLeft = max(0, t.tradeLeft-qty)
+}
@@ -0,0 +1,65 @@
+package sfclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTouchOffered(t *testing.T) {
+	var touch Touch
+	q := StockState{Bid: 99, BidSize: 10, Ask: 101, AskSize: 20}
+	touch.Update(q)
+
+	touch.Take(true, 15)
+	if price, qty := touch.Offered(true); price != 101 || qty != 5 {
+		t.Errorf("expected 5 left at 101, got %d at %d", qty, price)
+	}
+	if price, qty := touch.Offered(false); price != 99 || qty != 10 {
+		t.Errorf("expected the bid untouched, got %d at %d", qty, price)
+	}
+
+	// Size taken stays taken while the price holds, so only what is added
+	// is offered again
+	touch.Update(q)
+	if _, qty := touch.Offered(true); qty != 5 {
+		t.Errorf("expected the same quote to offer 5, got %d", qty)
+	}
+	q.AskSize = 30
+	touch.Update(q)
+	if _, qty := touch.Offered(true); qty != 15 {
+		t.Errorf("expected 15 offered after the ask grew, got %d", qty)
+	}
+
+	q.Ask, q.AskSize = 102, 8
+	touch.Update(q)
+	if price, qty := touch.Offered(true); price != 102 || qty != 8 {
+		t.Errorf("expected a new price to offer all of its size, got %d at %d", qty, price)
+	}
+}
+
+func TestTouchTraded(t *testing.T) {
+	var touch Touch
+	at := bookTime.Add(time.Second)
+	q := StockState{Bid: 99, Ask: 101, Last: 100, LastSize: 10, LastTrade: at}
+	touch.Update(q)
+
+	touch.Allot(4)
+	if when, price, qty := touch.Traded(); !when.Equal(at) || price != 100 || qty != 6 {
+		t.Errorf("expected 6 left of the trade at 100, got %d at %d at %v", qty, price, when)
+	}
+
+	// The trade is only allotted on the quote that first shows it
+	touch.Update(q)
+	if _, _, qty := touch.Traded(); qty != 0 {
+		t.Errorf("expected the trade withdrawn, got %d left", qty)
+	}
+
+	// A recorded execution is not printed again when a quote shows it
+	later := at.Add(time.Second)
+	touch.Print(later, 102, 3)
+	q.Last, q.LastSize, q.LastTrade = 102, 3, later
+	touch.Update(q)
+	if _, _, qty := touch.Traded(); qty != 0 {
+		t.Errorf("expected the printed execution not to be repeated, got %d", qty)
+	}
+}
@@ -2,7 +2,7 @@ package sfclient
 
 import (
 	"github.com/gorilla/websocket"
-	"log"
+	"log/slog"
 	"net/url"
 	"path"
 	"time"
@@ -14,9 +14,11 @@ type TickMessage struct {
 }
 
 type TickListener struct {
-	url      *url.URL
-	close    chan struct{}
-	messages chan *TickMessage
+	url         *url.URL
+	close       chan struct{}
+	messages    chan *TickMessage
+	instruments *Instruments
+	logger      *slog.Logger
 }
 
 func (t *TickListener) Listen() (<-chan *TickMessage, error) {
@@ -40,16 +42,18 @@ func (t *TickListener) Listen() (<-chan *TickMessage, error) {
 				err = coalesceErr(err, msg)
 				if err != nil {
 					// Probably a connection error. Attempt reconnect
-					log.Printf("websocket read err: %v", err)
+					t.logger.Warn("websocket read failed, reconnecting", "err", err)
 					c.Close()
 					c, _, err = websocket.DefaultDialer.Dial(t.url.String(), nil)
+					t.instruments.reconnect(StreamTicks, err)
 					if err != nil {
-						log.Printf("websocket reconnect fail: %v", err)
+						t.logger.Error("websocket reconnect failed", "err", err)
 						return
 					}
-					// Reconnect success
+					t.logger.Info("websocket reconnected")
 				} else {
 					// Send message
+					t.record(msg)
 					t.messages <- msg
 				}
 			}
@@ -59,6 +63,10 @@ func (t *TickListener) Listen() (<-chan *TickMessage, error) {
 	return t.messages, nil
 }
 
+func (t *TickListener) record(msg *TickMessage) {
+	t.instruments.tick(msg.Quote, time.Now())
+}
+
 func (t *TickListener) Close() {
 	t.close <- struct{}{}
 }
@@ -70,9 +78,11 @@ func (c *Client) VenueTicker(account string, venue Venue) (*TickListener, error)
 	}
 
 	return &TickListener{
-		url:      u,
-		close:    make(chan struct{}, 1), // Ensure buffered so close won't block
-		messages: make(chan *TickMessage, 100),
+		url:         u,
+		close:       make(chan struct{}, 1), // Ensure buffered so close won't block
+		messages:    make(chan *TickMessage, 100),
+		instruments: c.instruments,
+		logger:      tradingLogger(c.logger, account, venue, "").With(LogStream, StreamTicks),
 	}, nil
 }
 
@@ -83,9 +93,11 @@ func (c *Client) StockTicker(account string, venue Venue, stock Symbol) (*TickLi
 	}
 
 	return &TickListener{
-		url:      u,
-		close:    make(chan struct{}, 1), // Ensure buffered so close won't block
-		messages: make(chan *TickMessage, 100),
+		url:         u,
+		close:       make(chan struct{}, 1), // Ensure buffered so close won't block
+		messages:    make(chan *TickMessage, 100),
+		instruments: c.instruments,
+		logger:      tradingLogger(c.logger, account, venue, stock).With(LogStream, StreamTicks),
 	}, nil
 }
 
@@ -111,9 +123,11 @@ type FillMessage struct {
 }
 
 type FillListener struct {
-	url      *url.URL
-	close    chan struct{}
-	messages chan *FillMessage
+	url         *url.URL
+	close       chan struct{}
+	messages    chan *FillMessage
+	instruments *Instruments
+	logger      *slog.Logger
 }
 
 func (t *FillListener) Listen() (<-chan *FillMessage, error) {
@@ -137,16 +151,18 @@ func (t *FillListener) Listen() (<-chan *FillMessage, error) {
 				err = coalesceErr(err, msg)
 				if err != nil {
 					// Probably a connection error. Attempt reconnect
-					log.Printf("websocket read err: %v", err)
+					t.logger.Warn("websocket read failed, reconnecting", "err", err)
 					c.Close()
 					c, _, err = websocket.DefaultDialer.Dial(t.url.String(), nil)
+					t.instruments.reconnect(StreamFills, err)
 					if err != nil {
-						log.Printf("websocket reconnect fail: %v", err)
+						t.logger.Error("websocket reconnect failed", "err", err)
 						return
 					}
-					// Reconnect success
+					t.logger.Info("websocket reconnected")
 				} else {
 					// Send message
+					t.record(msg)
 					t.messages <- msg
 				}
 			}
@@ -156,6 +172,10 @@ func (t *FillListener) Listen() (<-chan *FillMessage, error) {
 	return t.messages, nil
 }
 
+func (t *FillListener) record(msg *FillMessage) {
+	t.instruments.fill(msg, time.Now())
+}
+
 func (t *FillListener) Close() {
 	t.close <- struct{}{}
 }
@@ -167,9 +187,11 @@ func (c *Client) VenueFills(account string, venue Venue) (*FillListener, error)
 	}
 
 	return &FillListener{
-		url:      u,
-		close:    make(chan struct{}, 1), // Ensure buffered so cannot block
-		messages: make(chan *FillMessage, 100),
+		url:         u,
+		close:       make(chan struct{}, 1), // Ensure buffered so cannot block
+		messages:    make(chan *FillMessage, 100),
+		instruments: c.instruments,
+		logger:      tradingLogger(c.logger, account, venue, "").With(LogStream, StreamFills),
 	}, nil
 }
 
@@ -180,8 +202,10 @@ func (c *Client) StockFills(account string, venue Venue, stock Symbol) (*FillLis
 	}
 
 	return &FillListener{
-		url:      u,
-		close:    make(chan struct{}, 1), // Ensure buffered so cannot block
-		messages: make(chan *FillMessage, 100),
+		url:         u,
+		close:       make(chan struct{}, 1), // Ensure buffered so cannot block
+		messages:    make(chan *FillMessage, 100),
+		instruments: c.instruments,
+		logger:      tradingLogger(c.logger, account, venue, stock).With(LogStream, StreamFills),
 	}, nil
 }
@@ -1,7 +1,6 @@
 package sfclient
 
 import (
-	"fmt"
 	"testing"
 )
 
@@ -31,7 +30,7 @@ func TestConnectVenueTicker(t *testing.T) {
 		price := i + 1
 		br, err := c.BuyOrder(testAccount, testVenue, testSymbol, price, 10, TypeMarket)
 		if err = checkerr(br.APIResponse, err); err != nil {
-			fmt.Println("error placing buy order: %v", err)
+			t.Errorf("error placing buy order: %v", err)
 			return
 		}
 	}
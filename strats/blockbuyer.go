@@ -0,0 +1,120 @@
+package strats
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+type blockBuyer struct {
+	BaseStrategy
+	trader Trader
+	logger *slog.Logger
+
+	stats   *sfclient.MarketStats
+	bid     int
+	bidSize int
+	toBuy   int
+	done    func()
+
+	// placed holds the orders whose fills count towards the position and
+	// are yet to be reported
+	placed map[int]*placedOrder
+}
+
+// NewBlockBuyer returns a Strategy that accumulates a large position of
+// toBuy shares by only taking the bid when it is above the average ask over
+// window. done, if not nil, is called once the position has been bought.
+func NewBlockBuyer(toBuy int, window sfclient.WindowSize, done func()) Strategy {
+	return &blockBuyer{
+		logger: slog.Default(),
+		stats:  sfclient.NewMarketStats(window, 0.01),
+		toBuy:  toBuy,
+		done:   done,
+		placed: make(map[int]*placedOrder),
+	}
+}
+
+func (b *blockBuyer) SetLogger(logger *slog.Logger) {
+	b.logger = logger
+}
+
+func (b *blockBuyer) Start(t Trader) error {
+	b.trader = t
+	return nil
+}
+
+func (b *blockBuyer) OnTick(msg *sfclient.TickMessage) {
+	if !msg.OK {
+		return
+	}
+	b.stats.Add(msg.Quote)
+	b.bid, b.bidSize = msg.Quote.Bid, msg.Quote.BidSize
+}
+
+func (b *blockBuyer) OnTimer(now time.Time) {
+	asks := b.stats.Summary(sfclient.FieldAsk)
+
+	// Need at least one quote before there is an average to compare against
+	if b.toBuy <= 0 || asks.Count == 0 {
+		return
+	}
+
+	avgAsk := int(asks.Mean)
+	bid, bidSize := b.bid, b.bidSize
+	b.logger.Debug("checking bid", "avgAsk", avgAsk, "bid", bid, "bidSize", bidSize)
+
+	// If current buying price is below average asking price, SELL SELL SELL
+	if bid > avgAsk && bidSize > 0 {
+		b.logger.Info("executing bid", "price", bid, "qty", bidSize)
+		resp, err := b.trader.Buy(bid, bidSize, sfclient.TypeImmediateOrCancel)
+		if err != nil {
+			b.logger.Error("error buying", "err", err)
+			return
+		}
+		// An IOC is usually closed by the time it is acknowledged, and one
+		// that took nothing has no fills to wait for
+		placed := &placedOrder{total: -1}
+		if !resp.Open {
+			placed.total = resp.TotalFilled
+		}
+		if !placed.done() {
+			b.placed[resp.ID] = placed
+		}
+	}
+}
+
+// OnFill counts executions of our bids, which may only be reported after
+// the order has been acknowledged.
+func (b *blockBuyer) OnFill(msg *sfclient.FillMessage) {
+	placed, ok := b.placed[msg.Order.ID]
+	if !ok {
+		return
+	}
+	placed.counted += msg.Filled
+	if !msg.Order.Open || placed.done() {
+		delete(b.placed, msg.Order.ID)
+	}
+
+	b.toBuy -= msg.Filled
+	b.logger.Info("bid filled", "filled", msg.Filled, "left", max(0, b.toBuy))
+	if b.toBuy <= 0 && b.done != nil {
+		b.done()
+		b.done = nil
+	}
+}
+
+// OnOrderUpdate forgets bids that have closed once every fill they made has
+// been counted.
+func (b *blockBuyer) OnOrderUpdate(order *sfclient.OrderResponse) {
+	placed, ok := b.placed[order.ID]
+	if !ok || order.Open {
+		return
+	}
+
+	placed.total = order.TotalFilled
+	if placed.done() {
+		delete(b.placed, order.ID)
+	}
+}
@@ -0,0 +1,48 @@
+package strats
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+func TestBlockBuyerForgetsClosedBids(t *testing.T) {
+	var done bool
+	b := NewBlockBuyer(15, sfclient.WindowSize{Count: 10}, func() { done = true }).(*blockBuyer)
+	b.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ft := &fakeTrader{}
+	if err := b.Start(ft); err != nil {
+		t.Fatalf("error starting: %v", err)
+	}
+
+	// The bid is above the average ask, so it is taken
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid, tick.Quote.BidSize, tick.Quote.Ask = 105, 10, 100
+	b.OnTick(tick)
+
+	// An IOC acknowledged before it executed is forgotten once it closes
+	// without trading
+	b.OnTimer(time.Now())
+	if len(ft.orders) != 1 || len(b.placed) != 1 {
+		t.Fatalf("expected a bid to be placed and tracked, got %d placed, %d tracked", len(ft.orders), len(b.placed))
+	}
+	b.OnOrderUpdate(&sfclient.OrderResponse{ID: ft.orders[0].ID, Direction: "buy"})
+	if len(b.placed) != 0 {
+		t.Errorf("expected the closed bid to be forgotten, got %d tracked", len(b.placed))
+	}
+
+	// One that traded on arrival is kept until its fill is counted
+	ft.filled = true
+	b.OnTimer(time.Now())
+	bid := ft.orders[1]
+	if _, ok := b.placed[bid.ID]; !ok {
+		t.Fatal("expected a bid that traded to be kept until its fill")
+	}
+	b.OnFill(&sfclient.FillMessage{Order: *bid, Price: bid.Price, Filled: bid.TotalFilled})
+	if len(b.placed) != 0 || b.toBuy != 5 || done {
+		t.Errorf("expected 5 left to buy and nothing tracked, got %d left, %d tracked", b.toBuy, len(b.placed))
+	}
+}
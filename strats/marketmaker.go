@@ -1,175 +1,245 @@
 package strats
 
 import (
-	"log"
-	"sync"
+	"log/slog"
+	"time"
 
 	"github.com/ifross89/stockfighter/sfclient"
 )
 
-type orderInFlight struct {
-	id   int
-	resp chan struct{}
-}
-
 type simpleMarketMaker struct {
-	account string
-	venue   sfclient.Venue
-	stock   sfclient.Symbol
-	fillch  <-chan *sfclient.FillMessage
-	tickch  <-chan *sfclient.TickMessage
-	client  *sfclient.Client
-
-	mu              *sync.RWMutex
+	trader Trader
+	logger *slog.Logger
+
 	latestBid       *sfclient.AskBid
 	latestAsk       *sfclient.AskBid
 	maxExposure     int
 	currentExposure int
 
-	inFlight chan orderInFlight
-}
+	// ids of the quotes currently resting on the book, 0 if none
+	bidID int
+	askID int
 
-func New(apiKey string, account string, venue sfclient.Venue, stock sfclient.Symbol, maxExposure int) (*simpleMarketMaker, error) {
-	c := sfclient.New(apiKey)
+	// placed holds every order placed that may still fill, including
+	// quotes since replaced, whose fills can arrive after the cancel
+	placed map[int]*placedOrder
+}
 
-	sl, err := c.StockTicker(account, venue, stock)
-	if err != nil {
-		return nil, err
-	}
+// placedOrder counts the fills of an order until all of them have been
+// reported.
+type placedOrder struct {
+	counted int
 
-	tick, err := sl.Listen()
+	// total is the quantity the order filled, once it has closed; fills
+	// from before it closed may still be to come. -1 while it is open.
+	total int
+}
 
-	fl, err := c.StockFills(account, venue, stock)
-	if err != nil {
-		return nil, err
-	}
+// done reports whether the order has closed and all of its fills have been
+// counted.
+func (p *placedOrder) done() bool {
+	return p.total >= 0 && p.counted >= p.total
+}
 
-	fills, err := fl.Listen()
-	if err != nil {
-		return nil, err
+// NewMarketMaker returns a Strategy that continuously quotes both sides of
+// the book, never holding more than maxExposure shares long or short.
+func NewMarketMaker(maxExposure int) Strategy {
+	return &simpleMarketMaker{
+		latestBid:   &sfclient.AskBid{IsBuy: true},
+		latestAsk:   &sfclient.AskBid{},
+		maxExposure: maxExposure,
+		logger:      slog.Default(),
+		placed:      make(map[int]*placedOrder),
 	}
+}
 
-	return &simpleMarketMaker{fillch: fills, tickch: tick, maxExposure: maxExposure, client: c, account: account, venue: venue, stock: stock}, nil
+func (mm *simpleMarketMaker) SetLogger(logger *slog.Logger) {
+	mm.logger = logger
 }
 
 // calculate current spread to use
 func (mm *simpleMarketMaker) currentSpread() (ask *sfclient.AskBid, bid *sfclient.AskBid) {
 	const risk = 5             // lower is more risky
 	const defaultSpreadPc = 75 // in percent
+	const skewPc = 50          // how far a full position moves the mid, in percent of the half spread
 
 	ask, bid = &sfclient.AskBid{}, &sfclient.AskBid{IsBuy: true}
-	mm.mu.RLock()
 	ask.Price, ask.Quantity = mm.latestAsk.Price, mm.latestAsk.Quantity
 	bid.Price, bid.Quantity = mm.latestBid.Price, mm.latestBid.Quantity
 	exposure := mm.currentExposure
-	mm.mu.RUnlock()
 
 	mid := (ask.Price + bid.Price) / 2
 	halfSpread := (ask.Price - bid.Price) / 2
 
-	ask.Price = mid - ((halfSpread * defaultSpreadPc) / 100)
-	bid.Price = mid + ((halfSpread * defaultSpreadPc) / 100)
+	// Skew the quotes against our inventory: when long, lower both prices so
+	// we are more likely to sell, and vice versa when short.
+	if mm.maxExposure > 0 {
+		mid -= (halfSpread * skewPc * exposure) / (100 * mm.maxExposure)
+	}
+
+	ask.Price = mid + ((halfSpread * defaultSpreadPc) / 100)
+	bid.Price = mid - ((halfSpread * defaultSpreadPc) / 100)
 
 	// Only risk 1/5 of the way to the limit
 	bid.Quantity = (mm.maxExposure - exposure) / risk
-	ask.Quantity(mm.maxExposure+exposure) / risk
+	ask.Quantity = (mm.maxExposure + exposure) / risk
+
+	// Never quote a size that could take us through the limit
+	if exposure+bid.Quantity > mm.maxExposure {
+		bid.Quantity = mm.maxExposure - exposure
+	}
+	if exposure-ask.Quantity < -mm.maxExposure {
+		ask.Quantity = mm.maxExposure + exposure
+	}
 	return ask, bid
 }
 
-func (mm *simpleMarketMaker) execute() {
-	toAsk, toBid := mm.currentSpread()
+// hasMarket reports whether both sides of the book have been seen, which is
+// required before a sensible spread can be quoted.
+func (mm *simpleMarketMaker) hasMarket() bool {
+	return mm.latestAsk.Price > 0 && mm.latestBid.Price > 0 && mm.latestAsk.Price > mm.latestBid.Price
+}
 
-	wg := &sync.WaitGroup{}
-	waitChan := make(chan struct{})
-	var bidID int
-	var askID int
+func (mm *simpleMarketMaker) cancel(id int, side string) {
+	if id == 0 {
+		return
+	}
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		bidResp, err := mm.client.BuyOrder(mm.account, mm.venue, mm.stock, toBid.Price, toBid.Quantity, sfclient.TypeLimit)
-		if err != nil {
-			log.Printf("error executing bid: %v", err)
-			return
-		} else if !bidResp.OK {
-			log.Printf("server error from bid: %s", bidResp.Error)
-			return
-		}
-		bidID = bidResp.ID
-		mm.inFlight <- orderInFlight{id: bidID, resp: waitChan}
-	}()
+	r, err := mm.trader.Cancel(id)
+	if err != nil {
+		mm.logger.Error("error cancelling quote", "side", side, sfclient.LogOrderID, id, "err", err)
+		return
+	}
 
-	go func() {
-		defer wg.Done()
-		askResp, err := mm.client.SellOrder(mm.account, mm.venue, mm.stock, toAsk.Price, toAsk.Quantity, sfclient.TypeLimit)
-		if err != nil {
-			log.Printf("error executing bid: %v", err)
-			return
-		} else if !askResp.OK {
-			log.Printf("server error from bid: %s", askResp.Error)
-			return
-		}
-		askID = askResp.ID
-		mm.inFlight <- orderInFlight{id: askID, resp: waitChan}
-	}()
-	wg.Wait()
-	// send id 0 to let the listener know that we aren't waiting on any more
-	mm.inFlight <- orderInFlight{id: 0, resp: waitChan}
-
-	// when the listener has received a fill, cancel the others
-	<-waitChan
-
-	// Now cancel the orders
-	go func() {
-		r, err := mm.client.CancelOrder(mm.venue, mm.stock, bidID)
+	// Anything filled before the cancel landed is reported on the fills
+	// channel, possibly after this, and counted by OnFill as the order
+	// stays in placed until all of it has been.
+	mm.logger.Info("quote cancelled", "side", side, sfclient.LogOrderID, id, "filled", r.TotalFilled)
+}
+
+// requote pulls any resting quotes and replaces them with a fresh pair
+// around the current market.
+func (mm *simpleMarketMaker) requote() {
+	mm.cancel(mm.bidID, "bid")
+	mm.cancel(mm.askID, "ask")
+	mm.bidID, mm.askID = 0, 0
+
+	if !mm.hasMarket() {
+		return
+	}
+
+	toAsk, toBid := mm.currentSpread()
+	if toBid.Price >= toAsk.Price {
+		mm.logger.Warn("refusing to quote crossed spread", "bid", toBid.Price, "ask", toAsk.Price)
+		return
+	}
+
+	if toBid.Quantity > 0 && toBid.Price > 0 {
+		bidResp, err := mm.trader.Buy(toBid.Price, toBid.Quantity, sfclient.TypeLimit)
 		if err != nil {
-			log.Printf("error canceling bid: %v", err)
-		} else if !r.OK {
-			log.Printf("error cancelling bid: %v", err)
+			mm.logger.Error("error placing bid", "price", toBid.Price, "qty", toBid.Quantity, "err", err)
 		} else {
-			log.Println("bid %d successfully cancelled")
+			mm.placed[bidResp.ID] = &placedOrder{total: -1}
+			if bidResp.Open {
+				mm.bidID = bidResp.ID
+			}
 		}
-	}()
+	}
 
-	go func() {
-		r, err := mm.client.CancelOrder(mm.venue, mm.stock, askID)
+	if toAsk.Quantity > 0 {
+		askResp, err := mm.trader.Sell(toAsk.Price, toAsk.Quantity, sfclient.TypeLimit)
 		if err != nil {
-			log.Printf("error canceling ask: %v", err)
-		} else if !r.OK {
-			log.Printf("error cancelling ask: %v", err)
+			mm.logger.Error("error placing ask", "price", toAsk.Price, "qty", toAsk.Quantity, "err", err)
 		} else {
-			log.Println("ask %d successfully cancelled")
+			mm.placed[askResp.ID] = &placedOrder{total: -1}
+			if askResp.Open {
+				mm.askID = askResp.ID
+			}
 		}
-	}()
+	}
+
+	mm.logger.Info("quoting",
+		"bid", toBid.Price, "bid_qty", toBid.Quantity, "bid_id", mm.bidID,
+		"ask", toAsk.Price, "ask_qty", toAsk.Quantity, "ask_id", mm.askID)
 }
 
-type receivedFill struct {
+func (mm *simpleMarketMaker) Start(t Trader) error {
+	mm.trader = t
+	return nil
 }
 
-func (mm *simpleMarketMaker) listen() {
-	idsInFlight := make(map[int]struct{})
-	fillsReceived := make(map[int]struct{})
-
-	for {
-		select {
-		case msg := <-mm.tickch:
-			if msg.OK {
-				mm.mu.Lock()
-				// update latest bid / ask prices
-				if msg.Quote.Ask > 0 {
-					mm.latestAsk.Price = msg.Quote.Ask
-				}
-				mm.latestAsk.Quantity = msg.Quote.AskSize
-				if msg.Quote.Bid > 0 {
-					mm.latestBid.Price = msg.Quote.Bid
-				}
-				mm.latestBid.Quantity = msg.Quote.BidSize
-				mm.mu.Unlock()
-			}
-		case fill := <-mm.fillch:
-			if fill.OK {
-				fill.Account
-			}
+func (mm *simpleMarketMaker) OnTick(msg *sfclient.TickMessage) {
+	// update latest bid / ask prices
+	if msg.Quote.Ask > 0 {
+		mm.latestAsk.Price = msg.Quote.Ask
+	}
+	mm.latestAsk.Quantity = msg.Quote.AskSize
+	if msg.Quote.Bid > 0 {
+		mm.latestBid.Price = msg.Quote.Bid
+	}
+	mm.latestBid.Quantity = msg.Quote.BidSize
+
+	if mm.bidID == 0 && mm.askID == 0 {
+		mm.requote()
+	}
+}
+
+// OnFill updates the exposure for a fill against any order we placed,
+// including quotes already replaced or filled on arrival, and requotes at
+// the new exposure.
+func (mm *simpleMarketMaker) OnFill(fill *sfclient.FillMessage) {
+	id := fill.Order.ID
+	placed, ok := mm.placed[id]
+	if !ok {
+		return
+	}
+	placed.counted += fill.Filled
+
+	if fill.Order.Direction == "buy" {
+		mm.currentExposure += fill.Filled
+	} else {
+		mm.currentExposure -= fill.Filled
+	}
+
+	mm.logger.Info("quote filled", "side", fill.Order.Direction, sfclient.LogOrderID, id,
+		"price", fill.Price, "qty", fill.Filled, "exposure", mm.currentExposure)
+
+	if !fill.Order.Open || placed.done() {
+		delete(mm.placed, id)
+	}
+	if !fill.Order.Open {
+		switch id {
+		case mm.bidID:
+			mm.bidID = 0
+		case mm.askID:
+			mm.askID = 0
 		}
 	}
+	mm.requote()
+}
+
+// OnOrderUpdate forgets orders that have closed, such as cancelled quotes,
+// once every fill they made has been counted.
+func (mm *simpleMarketMaker) OnOrderUpdate(order *sfclient.OrderResponse) {
+	placed, ok := mm.placed[order.ID]
+	if !ok || order.Open {
+		return
+	}
+
+	placed.total = order.TotalFilled
+	if placed.done() {
+		delete(mm.placed, order.ID)
+	}
+}
+
+// OnTimer refreshes the quotes so they follow the market even when nothing
+// is trading against them.
+func (mm *simpleMarketMaker) OnTimer(now time.Time) {
+	mm.requote()
+}
+
+func (mm *simpleMarketMaker) Stop() {
+	mm.cancel(mm.bidID, "bid")
+	mm.cancel(mm.askID, "ask")
+	mm.bidID, mm.askID = 0, 0
 }
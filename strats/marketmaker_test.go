@@ -0,0 +1,229 @@
+package strats
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// fakeTrader acknowledges every order, recording it, and fills nothing.
+type fakeTrader struct {
+	orders    []*sfclient.OrderResponse
+	cancelled []int
+
+	// filled makes orders trade completely on arrival
+	filled bool
+}
+
+func (f *fakeTrader) place(direction string, price, qty int) (*sfclient.OrderResponse, error) {
+	o := &sfclient.OrderResponse{
+		ID:               len(f.orders) + 1,
+		Direction:        direction,
+		OriginalQuantity: qty,
+		Quantity:         qty,
+		Price:            price,
+		Open:             !f.filled,
+	}
+	if f.filled {
+		o.Quantity, o.TotalFilled = 0, qty
+	}
+	f.orders = append(f.orders, o)
+	return o, nil
+}
+
+func (f *fakeTrader) Buy(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return f.place("buy", price, qty)
+}
+
+func (f *fakeTrader) Sell(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	return f.place("sell", price, qty)
+}
+
+func (f *fakeTrader) Cancel(id int) (*sfclient.CancelOrderResponse, error) {
+	f.cancelled = append(f.cancelled, id)
+	return &sfclient.CancelOrderResponse{}, nil
+}
+
+// quotes returns the last bid and ask placed, nil if there was none.
+func (f *fakeTrader) quotes(since int) (bid, ask *sfclient.OrderResponse) {
+	for _, o := range f.orders[since:] {
+		if o.Direction == "buy" {
+			bid = o
+		} else {
+			ask = o
+		}
+	}
+	return bid, ask
+}
+
+func newTestMarketMaker(t *testing.T, maxExposure int) (*simpleMarketMaker, *fakeTrader) {
+	t.Helper()
+	mm := NewMarketMaker(maxExposure).(*simpleMarketMaker)
+	mm.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ft := &fakeTrader{}
+	if err := mm.Start(ft); err != nil {
+		t.Fatalf("error starting: %v", err)
+	}
+
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid, tick.Quote.Ask = 100, 110
+	mm.OnTick(tick)
+	return mm, ft
+}
+
+// fill reports qty of order o as filled, leaving it open if any remains.
+func fill(mm *simpleMarketMaker, o *sfclient.OrderResponse, qty int) {
+	o.Quantity -= qty
+	o.TotalFilled += qty
+	o.Open = o.Quantity > 0
+	mm.OnFill(&sfclient.FillMessage{Order: *o, Price: o.Price, Filled: qty, FilledAt: time.Now()})
+}
+
+func TestMarketMakerSkew(t *testing.T) {
+	for _, test := range []struct {
+		exposure int
+		bid, ask int
+	}{
+		// A half spread of 5 quoted at 75%, around a mid that moves down by
+		// up to half the half spread as the position grows long
+		{0, 102, 108},
+		{50, 101, 107},
+		{-50, 103, 109},
+		{100, 100, 106},
+		{-100, 104, 110},
+	} {
+		mm, ft := newTestMarketMaker(t, 100)
+		mm.currentExposure = test.exposure
+		n := len(ft.orders)
+		mm.OnTimer(time.Now())
+
+		ask, bid := mm.currentSpread()
+		if bid.Price != test.bid || ask.Price != test.ask {
+			t.Errorf("exposure %d: expected to quote %d/%d, got %d/%d", test.exposure, test.bid, test.ask, bid.Price, ask.Price)
+		}
+
+		// At the limit only the side that reduces the position is quoted
+		placedBid, placedAsk := ft.quotes(n)
+		if (placedBid != nil) != (test.exposure < 100) || (placedAsk != nil) != (test.exposure > -100) {
+			t.Errorf("exposure %d: placed bid %v and ask %v", test.exposure, placedBid, placedAsk)
+		}
+	}
+}
+
+func TestMarketMakerExposureCap(t *testing.T) {
+	const maxExposure = 50
+	mm, ft := newTestMarketMaker(t, maxExposure)
+
+	// Keep buying: the bids are a fifth of the room left, so shrink as the
+	// position grows until they would be for nothing
+	for i := 0; i < 100; i++ {
+		bid, _ := ft.quotes(0)
+		if bid == nil || !bid.Open {
+			break
+		}
+		if mm.currentExposure+bid.Quantity > maxExposure {
+			t.Fatalf("bid for %d at exposure %d could exceed the limit of %d", bid.Quantity, mm.currentExposure, maxExposure)
+		}
+		fill(mm, bid, bid.Quantity)
+	}
+	if mm.currentExposure > maxExposure || mm.currentExposure <= maxExposure-5 {
+		t.Errorf("expected to stop buying within 5 of the limit %d, got exposure %d", maxExposure, mm.currentExposure)
+	}
+	if mm.bidID != 0 {
+		t.Errorf("expected no bid near the limit, got order %d", mm.bidID)
+	}
+}
+
+func TestMarketMakerLateFills(t *testing.T) {
+	mm, ft := newTestMarketMaker(t, 100)
+	oldBid, oldAsk := ft.quotes(0)
+
+	// Requoting cancels the old quotes, but fills from before the cancel
+	// still count
+	mm.OnTimer(time.Now())
+	fill(mm, oldBid, 5)
+	fill(mm, oldAsk, 2)
+	if mm.currentExposure != 3 {
+		t.Errorf("expected exposure 3 after fills of replaced quotes, got %d", mm.currentExposure)
+	}
+
+	// Orders that trade on arrival are never open, but are still ours
+	ft.filled = true
+	n := len(ft.orders)
+	mm.OnTimer(time.Now())
+	ft.filled = false
+	bid, _ := ft.quotes(n)
+	if bid == nil {
+		t.Fatal("expected a bid to be placed")
+	}
+	mm.OnFill(&sfclient.FillMessage{Order: *bid, Price: bid.Price, Filled: bid.TotalFilled})
+	if want := 3 + bid.TotalFilled; mm.currentExposure != want {
+		t.Errorf("expected exposure %d after a fill on arrival, got %d", want, mm.currentExposure)
+	}
+
+	// Fills of orders that are not ours are ignored
+	mm.OnFill(&sfclient.FillMessage{Order: sfclient.OrderResponse{ID: 1000, Direction: "buy"}, Filled: 10})
+	if want := 3 + bid.TotalFilled; mm.currentExposure != want {
+		t.Errorf("expected a foreign fill to be ignored, got exposure %d", mm.currentExposure)
+	}
+}
+
+func TestMarketMakerRequoteOnFill(t *testing.T) {
+	mm, ft := newTestMarketMaker(t, 100)
+	bid, ask := ft.quotes(0)
+	if bid == nil || ask == nil {
+		t.Fatal("expected both sides to be quoted")
+	}
+
+	n := len(ft.orders)
+	fill(mm, bid, 10)
+
+	// The ask is pulled, along with what is left of the bid, and both
+	// sides quoted again at the new exposure
+	if len(ft.cancelled) != 2 || ft.cancelled[0] != bid.ID || ft.cancelled[1] != ask.ID {
+		t.Errorf("expected orders %d and %d cancelled, got %v", bid.ID, ask.ID, ft.cancelled)
+	}
+	newBid, newAsk := ft.quotes(n)
+	if newBid == nil || newAsk == nil {
+		t.Fatal("expected both sides to be quoted again")
+	}
+	if newBid.Quantity != 18 || newAsk.Quantity != 22 {
+		t.Errorf("expected to quote 18/22 at exposure 10, got %d/%d", newBid.Quantity, newAsk.Quantity)
+	}
+	if mm.bidID != newBid.ID || mm.askID != newAsk.ID {
+		t.Errorf("expected to track quotes %d/%d, got %d/%d", newBid.ID, newAsk.ID, mm.bidID, mm.askID)
+	}
+}
+
+func TestMarketMakerForgetsClosedOrders(t *testing.T) {
+	mm, ft := newTestMarketMaker(t, 100)
+	oldBid, oldAsk := ft.quotes(0)
+
+	// Cancelling the quotes that never filled forgets them
+	mm.OnTimer(time.Now())
+	for _, o := range []*sfclient.OrderResponse{oldBid, oldAsk} {
+		mm.OnOrderUpdate(&sfclient.OrderResponse{ID: o.ID, Direction: o.Direction})
+	}
+	if len(mm.placed) != 2 {
+		t.Errorf("expected only the new quotes to be kept, got %d orders", len(mm.placed))
+	}
+
+	// A quote cancelled after trading is kept until the fill is reported
+	bid, _ := ft.quotes(0)
+	mm.OnOrderUpdate(&sfclient.OrderResponse{ID: bid.ID, Direction: "buy", TotalFilled: 5})
+	if _, ok := mm.placed[bid.ID]; !ok {
+		t.Fatal("expected a cancelled quote with fills to come to be kept")
+	}
+
+	// The fill shows the order as it was when it traded, still open
+	fill(mm, bid, 5)
+	if _, ok := mm.placed[bid.ID]; ok {
+		t.Error("expected the quote to be forgotten once its fills were counted")
+	}
+	if mm.currentExposure != 5 {
+		t.Errorf("expected exposure 5, got %d", mm.currentExposure)
+	}
+}
@@ -0,0 +1,226 @@
+package strats
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// orderTracker wraps a Trader and remembers which orders are still open so
+// they can be cancelled when the runner exits. It queues the orders placed
+// and cancelled for the runner to pass to OnOrderUpdate.
+type orderTracker struct {
+	Trader
+	mu      *sync.Mutex
+	open    map[int]struct{}
+	updates []*sfclient.OrderResponse
+}
+
+func newOrderTracker(t Trader) *orderTracker {
+	return &orderTracker{Trader: t, mu: &sync.Mutex{}, open: make(map[int]struct{})}
+}
+
+func (o *orderTracker) track(resp *sfclient.OrderResponse) {
+	if resp == nil {
+		return
+	}
+	o.mu.Lock()
+	if resp.Open {
+		o.open[resp.ID] = struct{}{}
+	}
+	o.updates = append(o.updates, resp)
+	o.mu.Unlock()
+}
+
+func (o *orderTracker) forget(id int) {
+	o.mu.Lock()
+	delete(o.open, id)
+	o.mu.Unlock()
+}
+
+func (o *orderTracker) Buy(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	resp, err := o.Trader.Buy(price, qty, typ)
+	o.track(resp)
+	return resp, err
+}
+
+func (o *orderTracker) Sell(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error) {
+	resp, err := o.Trader.Sell(price, qty, typ)
+	o.track(resp)
+	return resp, err
+}
+
+func (o *orderTracker) Cancel(id int) (*sfclient.CancelOrderResponse, error) {
+	resp, err := o.Trader.Cancel(id)
+	if err == nil {
+		o.forget(id)
+		o.mu.Lock()
+		o.updates = append(o.updates, fromState(resp.OrderState))
+		o.mu.Unlock()
+	}
+	return resp, err
+}
+
+// pending returns and clears the queued order updates.
+func (o *orderTracker) pending() []*sfclient.OrderResponse {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	updates := o.updates
+	o.updates = nil
+	return updates
+}
+
+func fromState(s sfclient.OrderState) *sfclient.OrderResponse {
+	return &sfclient.OrderResponse{
+		Symbol:           s.Symbol,
+		Venue:            s.Venue,
+		Direction:        s.Direction,
+		OriginalQuantity: s.OriginalQuantity,
+		Quantity:         s.Quantity,
+		Price:            s.Price,
+		OrderType:        string(s.OrderType),
+		ID:               s.ID,
+		Account:          s.Account,
+		Timestamp:        s.Timestamp,
+		Fills:            s.Fills,
+		TotalFilled:      s.TotalFilled,
+		Open:             s.Open,
+	}
+}
+
+func (o *orderTracker) outstanding() []int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ids := make([]int, 0, len(o.open))
+	for id := range o.open {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Runner connects a Strategy to a Hub. It delivers ticks, fills and
+// timer events to the strategy on a single goroutine, stops on SIGINT or
+// SIGTERM and cancels any orders the strategy left open.
+type Runner struct {
+	hub      Hub
+	strategy Strategy
+	interval time.Duration
+	orders   *orderTracker
+	logger   *slog.Logger
+
+	tickch chan *sfclient.TickMessage
+	fillch chan *sfclient.FillMessage
+
+	stopOnce *sync.Once
+	done     chan struct{}
+}
+
+// NewRunner creates a runner for s. OnTimer is called every interval; an
+// interval of 0 disables the timer. The runner, and s if it implements
+// Logging, log through the hub's logger if it has one.
+func NewRunner(hub Hub, s Strategy, interval time.Duration) *Runner {
+	r := &Runner{
+		hub:      hub,
+		strategy: s,
+		interval: interval,
+		orders:   newOrderTracker(hub),
+		logger:   sfclient.LoggerOf(hub),
+		tickch:   make(chan *sfclient.TickMessage, 100),
+		fillch:   make(chan *sfclient.FillMessage, 100),
+		stopOnce: &sync.Once{},
+		done:     make(chan struct{}),
+	}
+
+	hub.RegisterToTick(r.tickch)
+	hub.RegisterToFills(r.fillch)
+	return r
+}
+
+// Stop asks the runner to shut down and stops the hub sending it ticks and
+// fills. It is safe to call from a strategy callback and more than once.
+func (r *Runner) Stop() {
+	r.stopOnce.Do(func() {
+		r.hub.UnregisterFromTick(r.tickch)
+		r.hub.UnregisterFromFills(r.fillch)
+		close(r.done)
+	})
+}
+
+// sendUpdates passes the orders placed or cancelled by the last callback to
+// OnOrderUpdate, until the strategy stops placing and cancelling orders.
+func (r *Runner) sendUpdates() {
+	for updates := r.orders.pending(); len(updates) > 0; updates = r.orders.pending() {
+		for _, u := range updates {
+			r.strategy.OnOrderUpdate(u)
+		}
+	}
+}
+
+// Run starts the strategy and blocks until Stop is called or the process is
+// signalled.
+func (r *Runner) Run() error {
+	if l, ok := r.strategy.(Logging); ok {
+		l.SetLogger(r.logger)
+	}
+	defer r.Stop()
+	if err := r.strategy.Start(r.orders); err != nil {
+		return err
+	}
+	defer r.shutdown()
+	r.sendUpdates()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	var timer <-chan time.Time
+	if r.interval > 0 {
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		select {
+		case <-r.done:
+			return nil
+		case sig := <-sigs:
+			r.logger.Info("shutting down", "signal", sig.String())
+			return nil
+		case msg := <-r.tickch:
+			if msg.OK {
+				r.strategy.OnTick(msg)
+			}
+		case msg := <-r.fillch:
+			if !msg.OK {
+				continue
+			}
+			if !msg.Order.Open {
+				r.orders.forget(msg.Order.ID)
+			}
+			r.strategy.OnFill(msg)
+			r.strategy.OnOrderUpdate(&msg.Order)
+		case now := <-timer:
+			r.strategy.OnTimer(now)
+		}
+		r.sendUpdates()
+	}
+}
+
+func (r *Runner) shutdown() {
+	r.strategy.Stop()
+
+	for _, id := range r.orders.outstanding() {
+		resp, err := r.orders.Cancel(id)
+		if err != nil {
+			r.logger.Error("error cancelling order on exit", sfclient.LogOrderID, id, "err", err)
+			continue
+		}
+		r.logger.Info("cancelled order on exit", sfclient.LogOrderID, id, "filled", resp.TotalFilled)
+	}
+}
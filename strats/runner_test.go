@@ -0,0 +1,235 @@
+package strats
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+	"github.com/ifross89/stockfighter/sfclient/sftest"
+)
+
+// buyOnce places two bids on the first tick and stops the runner once one
+// of them is closed.
+type buyOnce struct {
+	BaseStrategy
+	trader  Trader
+	placed  []int
+	updates []*sfclient.OrderResponse
+	stopped bool
+	stop    func()
+}
+
+func (b *buyOnce) Start(t Trader) error {
+	b.trader = t
+	return nil
+}
+
+func (b *buyOnce) OnTick(msg *sfclient.TickMessage) {
+	if len(b.placed) > 0 {
+		return
+	}
+	for _, price := range []int{msg.Quote.Bid, msg.Quote.Bid - 1} {
+		resp, err := b.trader.Buy(price, 10, sfclient.TypeLimit)
+		if err != nil {
+			panic(err)
+		}
+		b.placed = append(b.placed, resp.ID)
+	}
+}
+
+func (b *buyOnce) OnOrderUpdate(order *sfclient.OrderResponse) {
+	b.updates = append(b.updates, order)
+	if !order.Open {
+		b.stop()
+	}
+}
+
+func (b *buyOnce) Stop() {
+	b.stopped = true
+}
+
+func TestRunner(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	s := &buyOnce{}
+	r := NewRunner(hub, s, time.Hour)
+	s.stop = r.Stop
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid = 100
+	m.PushTick(tick)
+
+	// Wait for both bids before filling the first completely
+	for len(m.Orders()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	filled := m.Orders()[0]
+	filled.Open, filled.Quantity, filled.TotalFilled = false, 0, 10
+	m.PushFill(&sfclient.FillMessage{APIResponse: sfclient.APIResponse{OK: true}, Order: *filled, Filled: 10})
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runner failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runner did not stop")
+	}
+
+	if !s.stopped {
+		t.Error("strategy was not stopped")
+	}
+	// Both bids are reported as placed, then the first as filled
+	var ids []int
+	for _, u := range s.updates {
+		ids = append(ids, u.ID)
+	}
+	if want := []int{s.placed[0], s.placed[1], s.placed[0]}; !slices.Equal(ids, want) {
+		t.Errorf("expected updates for orders %v, got %v", want, ids)
+	} else if !s.updates[1].Open || s.updates[2].Open {
+		t.Errorf("expected the placed bid open and the filled one closed, got %+v", s.updates)
+	}
+
+	// The unfilled bid should have been cancelled on exit. The fake market
+	// does not know about the fill, so the first bid still looks open there.
+	for _, o := range m.Open() {
+		if o.ID == s.placed[1] {
+			t.Errorf("order %d left open on exit", o.ID)
+		}
+	}
+}
+
+// cancelOnce places a bid on the first tick and cancels it from the update
+// reporting it placed, then stops once the cancel is reported.
+type cancelOnce struct {
+	BaseStrategy
+	trader  Trader
+	updates []*sfclient.OrderResponse
+	stop    func()
+}
+
+func (c *cancelOnce) Start(t Trader) error {
+	c.trader = t
+	return nil
+}
+
+func (c *cancelOnce) OnTick(msg *sfclient.TickMessage) {
+	if len(c.updates) == 0 {
+		c.trader.Buy(msg.Quote.Bid, 10, sfclient.TypeLimit)
+	}
+}
+
+func (c *cancelOnce) OnOrderUpdate(order *sfclient.OrderResponse) {
+	c.updates = append(c.updates, order)
+	if order.Open {
+		c.trader.Cancel(order.ID)
+	} else {
+		c.stop()
+	}
+}
+
+func TestRunnerCancelUpdates(t *testing.T) {
+	m := sftest.NewMarket()
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	s := &cancelOnce{}
+	r := NewRunner(hub, s, 0)
+	s.stop = r.Stop
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	tick := &sfclient.TickMessage{APIResponse: sfclient.APIResponse{OK: true}}
+	tick.Quote.Bid = 100
+	m.PushTick(tick)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runner failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runner did not stop")
+	}
+
+	if len(s.updates) != 2 || !s.updates[0].Open || s.updates[1].Open || s.updates[0].ID != s.updates[1].ID {
+		t.Fatalf("expected the bid placed then cancelled, got %+v", s.updates)
+	}
+
+	// Once stopped the runner no longer listens, so the hub drops nothing
+	// on its account however much is sent
+	ticks := make(chan *sfclient.TickMessage, 200)
+	hub.RegisterToTick(ticks)
+	for i := 0; i < 200; i++ {
+		m.PushTick(tick)
+	}
+	for i := 0; i < 200; i++ {
+		<-ticks
+	}
+	if dropped, _ := hub.Dropped(); dropped != 0 {
+		t.Errorf("expected no ticks dropped after the runner stopped, got %d", dropped)
+	}
+}
+
+// logOnce logs from Start and stops the runner.
+type logOnce struct {
+	BaseStrategy
+	logger *slog.Logger
+	stop   func()
+}
+
+func (l *logOnce) SetLogger(logger *slog.Logger) {
+	l.logger = logger
+}
+
+func (l *logOnce) Start(t Trader) error {
+	l.logger.Info("started")
+	l.stop()
+	return nil
+}
+
+// loggedMarket is a market with its own logger, like a *sfclient.Client.
+type loggedMarket struct {
+	*sftest.Market
+	logger *slog.Logger
+}
+
+func (m loggedMarket) Logger() *slog.Logger {
+	return m.logger
+}
+
+func TestRunnerLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m := loggedMarket{sftest.NewMarket(), slog.New(slog.NewTextHandler(&buf, nil))}
+	hub, err := sfclient.NewStockHub(m, m, "EXB123456", "TESTEX", "FOOBAR")
+	if err != nil {
+		t.Fatalf("error creating hub: %v", err)
+	}
+	defer hub.Close()
+
+	s := &logOnce{}
+	r := NewRunner(hub, s, 0)
+	s.stop = r.Stop
+	if err := r.Run(); err != nil {
+		t.Fatalf("error running: %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "msg=started account=EXB123456 venue=TESTEX symbol=FOOBAR") {
+		t.Errorf("expected strategy to log with hub fields, got %q", got)
+	}
+}
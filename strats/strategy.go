@@ -0,0 +1,71 @@
+package strats
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ifross89/stockfighter/sfclient"
+)
+
+// Trader places and cancels orders in a single stock on behalf of a
+// strategy. *sfclient.StockHub satisfies it.
+type Trader interface {
+	Buy(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error)
+	Sell(price, qty int, typ sfclient.OrderType) (*sfclient.OrderResponse, error)
+	Cancel(id int) (*sfclient.CancelOrderResponse, error)
+}
+
+// Hub is the market access a Runner needs: somewhere to trade and streams
+// of ticks and fills. *sfclient.StockHub satisfies it.
+type Hub interface {
+	Trader
+	RegisterToTick(recv chan *sfclient.TickMessage)
+	RegisterToFills(recv chan *sfclient.FillMessage)
+	UnregisterFromTick(recv chan *sfclient.TickMessage)
+	UnregisterFromFills(recv chan *sfclient.FillMessage)
+}
+
+// Strategy is a trading algorithm driven by a Runner. All callbacks are made
+// from a single goroutine, so implementations need no locking of their own.
+type Strategy interface {
+	// Start is called once before any other callback. Orders must be placed
+	// through t so the runner can clean them up on exit.
+	Start(t Trader) error
+
+	// OnTick is called for every quote received from the tickertape.
+	OnTick(msg *sfclient.TickMessage)
+
+	// OnFill is called for every execution against one of our orders.
+	OnFill(msg *sfclient.FillMessage)
+
+	// OnOrderUpdate is called with the latest known state of one of our
+	// orders whenever it is placed, filled or cancelled: after the
+	// corresponding OnFill, or once the callback that placed or cancelled
+	// it returns.
+	OnOrderUpdate(order *sfclient.OrderResponse)
+
+	// OnTimer is called at the interval the runner was created with.
+	OnTimer(now time.Time)
+
+	// Stop is called once when the runner is shutting down, before any
+	// outstanding orders are cancelled.
+	Stop()
+}
+
+// Logging is implemented by strategies that log. A Runner calls SetLogger
+// before Start with its logger, which carries the hub's account, venue and
+// symbol.
+type Logging interface {
+	SetLogger(logger *slog.Logger)
+}
+
+// BaseStrategy implements every Strategy callback as a no-op. Embed it to
+// only implement the callbacks of interest.
+type BaseStrategy struct{}
+
+func (BaseStrategy) Start(t Trader) error                        { return nil }
+func (BaseStrategy) OnTick(msg *sfclient.TickMessage)            {}
+func (BaseStrategy) OnFill(msg *sfclient.FillMessage)            {}
+func (BaseStrategy) OnOrderUpdate(order *sfclient.OrderResponse) {}
+func (BaseStrategy) OnTimer(now time.Time)                       {}
+func (BaseStrategy) Stop()                                       {}